@@ -1,7 +1,6 @@
 package lru
 
 import (
-	"fmt"
 	"sync"
 	"time"
 
@@ -43,6 +42,8 @@ func New2Q(size int) (*TwoQueueCache, error) {
 	return New2QParamsWithExpire(size, 0, Default2QRecentRatio, Default2QGhostEntries)
 }
 
+// New2QWithExpire creates a new TwoQueueCache using the default
+// values for the parameters, whose entries expire after the given duration.
 func New2QWithExpire(size int, expire time.Duration) (*TwoQueueCache, error) {
 	return New2QParamsWithExpire(size, expire, Default2QRecentRatio, Default2QGhostEntries)
 }
@@ -53,20 +54,26 @@ func New2QParams(size int, recentRatio float64, ghostRatio float64) (*TwoQueueCa
 	return New2QParamsWithExpire(size, 0, recentRatio, ghostRatio)
 }
 
+// New2QParamsWithExpire creates a new TwoQueueCache using the provided
+// parameter values, whose entries expire after the given duration.
 func New2QParamsWithExpire(size int, expire time.Duration, recentRatio float64, ghostRatio float64) (*TwoQueueCache, error) {
 	if size <= 0 {
-		return nil, fmt.Errorf("invalid size")
+		return nil, ErrInvalidSize
 	}
 	if recentRatio < 0.0 || recentRatio > 1.0 {
-		return nil, fmt.Errorf("invalid recent ratio")
+		return nil, ErrInvalidRatio
 	}
 	if ghostRatio < 0.0 || ghostRatio > 1.0 {
-		return nil, fmt.Errorf("invalid ghost ratio")
+		return nil, ErrInvalidRatio
 	}
 
-	// Determine the sub-sizes
+	// Determine the sub-sizes. The ghost list always tracks at least one
+	// key, since small caches would otherwise round it down to nothing.
 	recentSize := int(float64(size) * recentRatio)
 	evictSize := int(float64(size) * ghostRatio)
+	if evictSize < 1 {
+		evictSize = 1
+	}
 
 	// Allocate the LRUs
 	recent, err := simplelru.NewLRUWithExpire(size, expire, nil)
@@ -120,6 +127,7 @@ func (c *TwoQueueCache) Add(key, value interface{}) {
 	c.AddEx(key, value, 0)
 }
 
+// AddEx adds a value to the cache with a per-key expiry.
 func (c *TwoQueueCache) AddEx(key, value interface{}, expire time.Duration) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -185,8 +193,8 @@ func (c *TwoQueueCache) Len() int {
 // Keys returns a slice of the keys in the cache.
 // The frequently used keys are first in the returned slice.
 func (c *TwoQueueCache) Keys() []interface{} {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	k1 := c.frequent.Keys()
 	k2 := c.recent.Keys()
 	return append(k1, k2...)
@@ -217,18 +225,18 @@ func (c *TwoQueueCache) Purge() {
 }
 
 // Contains is used to check if the cache contains a key
-// without updating recency or frequency.
+// without updating recency or frequency. Expired keys are removed.
 func (c *TwoQueueCache) Contains(key interface{}) bool {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	return c.frequent.Contains(key) || c.recent.Contains(key)
 }
 
 // Peek is used to inspect the cache value of a key
-// without updating recency or frequency.
+// without updating recency or frequency. Expired keys are removed.
 func (c *TwoQueueCache) Peek(key interface{}) (value interface{}, ok bool) {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	if val, ok := c.frequent.Peek(key); ok {
 		return val, ok
 	}
@@ -354,3 +354,23 @@ func Test2Q_Expire(t *testing.T) {
 		t.Fatal("not enough keys")
 	}
 }
+
+// Test that small caches with a zero-sized ghost list can be created
+func Test2Q_SmallSize(t *testing.T) {
+	l, err := New2QParams(1, Default2QRecentRatio, 0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if l.Len() != 1 || !l.Contains(2) {
+		t.Fatalf("2 should be the only entry")
+	}
+
+	// 1 was recently evicted, so re-adding it goes to frequent
+	l.Add(1, 1)
+	if n := l.frequent.Len(); n != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+}
@@ -34,6 +34,8 @@ func NewARC(size int) (*ARCCache, error) {
 	return NewARCWithExpire(size, 0)
 }
 
+// NewARCWithExpire creates an ARC of the given size whose entries expire
+// after the given duration.
 func NewARCWithExpire(size int, expire time.Duration) (*ARCCache, error) {
 	// Create the sub LRUs
 	b1, err := simplelru.NewLRUWithExpire(size, 0, nil)
@@ -93,6 +95,7 @@ func (c *ARCCache) Add(key, value interface{}) {
 	c.AddEx(key, value, c.expire)
 }
 
+// AddEx adds a value to the cache with a per-key expiry.
 func (c *ARCCache) AddEx(key, value interface{}, expire time.Duration) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -213,8 +216,8 @@ func (c *ARCCache) Len() int {
 
 // Keys returns all the cached keys
 func (c *ARCCache) Keys() []interface{} {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	k1 := c.t1.Keys()
 	k2 := c.t2.Keys()
 	return append(k1, k2...)
@@ -249,18 +252,18 @@ func (c *ARCCache) Purge() {
 }
 
 // Contains is used to check if the cache contains a key
-// without updating recency or frequency.
+// without updating recency or frequency. Expired keys are removed.
 func (c *ARCCache) Contains(key interface{}) bool {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	return c.t1.Contains(key) || c.t2.Contains(key)
 }
 
 // Peek is used to inspect the cache value of a key
-// without updating recency or frequency.
+// without updating recency or frequency. Expired keys are removed.
 func (c *ARCCache) Peek(key interface{}) (value interface{}, ok bool) {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	if val, ok := c.t1.Peek(key); ok {
 		return val, ok
 	}
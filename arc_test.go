@@ -424,3 +424,50 @@ func TestARC_Expire(t *testing.T) {
 		t.Fatal("not enough keys")
 	}
 }
+
+// Test that a scan does not evict the frequently used working set, unlike a
+// plain LRU
+func TestARC_ScanResistance(t *testing.T) {
+	arc, err := NewARC(100)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	lru, err := New(100)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Build a frequently used working set
+	for i := 0; i < 50; i++ {
+		arc.Add(i, i)
+		lru.Add(i, i)
+	}
+	for j := 0; j < 2; j++ {
+		for i := 0; i < 50; i++ {
+			arc.Get(i)
+			lru.Get(i)
+		}
+	}
+
+	// A scan of keys that are each used only once
+	for i := 1000; i < 2000; i++ {
+		arc.Add(i, i)
+		lru.Add(i, i)
+	}
+
+	arcHits, lruHits := 0, 0
+	for i := 0; i < 50; i++ {
+		if arc.Contains(i) {
+			arcHits++
+		}
+		if lru.Contains(i) {
+			lruHits++
+		}
+	}
+	if arcHits != 50 {
+		t.Fatalf("ARC should have kept the working set: %d", arcHits)
+	}
+	if lruHits != 0 {
+		t.Fatalf("LRU should have lost the working set: %d", lruHits)
+	}
+}
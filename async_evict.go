@@ -0,0 +1,67 @@
+package lru
+
+import "sync/atomic"
+
+// AsyncEvictPolicy decides what happens to an eviction reported by an
+// asynchronous callback while its queue is full.
+type AsyncEvictPolicy int
+
+const (
+	// AsyncEvictBlock makes the operation causing the eviction wait, with
+	// the cache locked, until the queue has room. No eviction is lost, but
+	// the callback must not use the cache or it may deadlock.
+	AsyncEvictBlock AsyncEvictPolicy = iota
+
+	// AsyncEvictDrop discards the eviction without calling the callback, so
+	// a slow callback never holds up the cache. Discarded evictions are
+	// counted by DroppedEvictions.
+	AsyncEvictDrop
+)
+
+// evictedEntry is an eviction waiting for the asynchronous callback
+type evictedEntry struct {
+	key, value interface{}
+}
+
+// enqueueEvicted is the callback given to the LRU in async mode. It is
+// called with the lock held.
+func (c *Cache) enqueueEvicted(key, value interface{}) {
+	if c.evictClosed {
+		c.onEvicted(key, value)
+		return
+	}
+	if c.evictPolicy == AsyncEvictDrop {
+		select {
+		case c.evictQueue <- evictedEntry{key, value}:
+		default:
+			atomic.AddUint64(&c.droppedEvictions, 1)
+		}
+		return
+	}
+	c.evictQueue <- evictedEntry{key, value}
+}
+
+// runEvictions calls the callback for queued evictions until the queue is
+// closed.
+func (c *Cache) runEvictions() {
+	defer close(c.evictDone)
+	for e := range c.evictQueue {
+		c.onEvicted(e.key, e.value)
+	}
+}
+
+// closeEvictions stops accepting evictions into the queue and waits for
+// the ones already queued to be reported.
+func (c *Cache) closeEvictions() {
+	c.lock.Lock()
+	c.evictClosed = true
+	close(c.evictQueue)
+	c.lock.Unlock()
+	<-c.evictDone
+}
+
+// DroppedEvictions returns the number of evictions discarded without
+// calling the callback because the async queue was full.
+func (c *Cache) DroppedEvictions() uint64 {
+	return atomic.LoadUint64(&c.droppedEvictions)
+}
@@ -0,0 +1,81 @@
+package lru
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAsyncEvict(t *testing.T) {
+	if _, err := NewWithOptions(WithSize(2), WithAsyncEvict(0, AsyncEvictBlock)); err == nil {
+		t.Fatalf("should fail on a zero buffer")
+	}
+
+	var mu sync.Mutex
+	var evicted []interface{}
+	release := make(chan struct{})
+	onEvicted := func(k interface{}, v interface{}) {
+		<-release
+		mu.Lock()
+		evicted = append(evicted, k)
+		mu.Unlock()
+	}
+	l, err := NewWithOptions(WithSize(1), WithEvict(onEvicted), WithAsyncEvict(8, AsyncEvictBlock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The callback is stuck, yet evictions do not hold up the cache
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+	if v, ok := l.Get(4); !ok || v != 4 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+
+	close(release)
+	l.Close()
+	mu.Lock()
+	if len(evicted) != 4 {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+	for i, k := range evicted {
+		if k != i {
+			t.Fatalf("bad order: %v", evicted)
+		}
+	}
+	mu.Unlock()
+
+	// After Close the callback runs directly
+	l.Add(5, 5)
+	if len(evicted) != 5 || evicted[4] != 4 {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+	l.Close()
+}
+
+func TestAsyncEvict_Drop(t *testing.T) {
+	release := make(chan struct{})
+	var evicted []interface{}
+	onEvicted := func(k interface{}, v interface{}) {
+		<-release
+		evicted = append(evicted, k)
+	}
+	l, err := NewWithOptions(WithSize(1), WithEvict(onEvicted), WithAsyncEvict(1, AsyncEvictDrop))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The drainer may take the first eviction off the queue before the
+	// next one arrives, so at most two are kept
+	for i := 0; i < 10; i++ {
+		l.Add(i, i)
+	}
+	close(release)
+	l.Close()
+	if n := uint64(len(evicted)) + l.DroppedEvictions(); n != 9 || len(evicted) > 2 {
+		t.Fatalf("bad: %v, %v dropped", evicted, l.DroppedEvictions())
+	}
+	if evicted[0] != 0 {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+}
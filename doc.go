@@ -1,9 +1,13 @@
-// Package lru provides three different LRU caches of varying sophistication.
+// Package lru provides several different LRU caches of varying sophistication.
 //
 // Cache is a simple LRU cache. It is based on the
 // LRU implementation in groupcache:
 // https://github.com/golang/groupcache/tree/master/lru
 //
+// NewWithOptions builds a Cache from composable options such as WithSize,
+// WithTTL and WithJanitor; the other Cache constructors are shorthands for
+// common combinations.
+//
 // TwoQueueCache tracks frequently used and recently used entries separately.
 // This avoids a burst of accesses from taking out frequently used entries,
 // at the cost of about 2x computational overhead and some extra bookkeeping.
@@ -16,6 +20,26 @@
 // ARC has been patented by IBM, so do not use it if that is problematic for
 // your program.
 //
+// ShardedCache spreads keys across several independently locked LRU caches
+// to reduce lock contention under heavy concurrent access. Recency is
+// tracked per shard rather than globally.
+//
+// RandomCache evicts the least recently used of a few randomly sampled
+// entries instead of maintaining a recency list, approximating LRU with O(1)
+// worst-case inserts.
+//
+// SLRUCache is a segmented LRU: entries are promoted from a probationary
+// segment to a protected one on their second hit, which keeps scans from
+// flushing frequently used entries.
+//
+// PolicyCache delegates the choice of victim to a pluggable
+// simplelru.EvictionPolicy, so custom eviction strategies can be used
+// without forking the package.
+//
+// TieredCache keeps a fixed size LRU in memory and spills the entries it
+// evicts into a user-supplied Overflow, such as a disk store, reading them
+// back into memory on a miss.
+//
 // All caches in this package take locks while operating, and are therefore
 // thread-safe for consumers.
 package lru
@@ -0,0 +1,58 @@
+package lru
+
+import (
+	"errors"
+
+	"github.com/iocn-io/golang-lru/simplelru"
+)
+
+var (
+	// ErrInvalidSize is returned when a cache is created or resized with a
+	// size that is not positive. It is the same error as
+	// simplelru.ErrInvalidSize, so either can be used with errors.Is.
+	ErrInvalidSize = simplelru.ErrInvalidSize
+
+	// ErrFrozen is returned by methods that would modify a cache made
+	// read-only by Freeze.
+	ErrFrozen = errors.New("Cache is frozen")
+
+	// ErrComputePanicked is returned to the callers waiting on a
+	// GetOrCompute or GetOrLoad call whose compute function panicked.
+	ErrComputePanicked = errors.New("Compute function panicked")
+
+	// ErrInvalidShardCount is returned when a ShardedCache is created with
+	// a shard count that is not positive.
+	ErrInvalidShardCount = errors.New("Must provide a positive shard count")
+
+	// ErrInvalidRatio is returned when a TwoQueueCache is created with a
+	// recent or ghost ratio outside 0 to 1.
+	ErrInvalidRatio = errors.New("Must provide a ratio between 0 and 1")
+
+	// ErrNoPolicy is returned when a PolicyCache is created without an
+	// eviction policy.
+	ErrNoPolicy = errors.New("Must provide an eviction policy")
+
+	// ErrInvalidSampleCount is returned when a RandomCache is created with
+	// a sample count that is not positive.
+	ErrInvalidSampleCount = errors.New("Must provide a positive sample count")
+
+	// ErrNoOverflow is returned when a TieredCache is created without an
+	// overflow tier.
+	ErrNoOverflow = errors.New("Must provide an overflow")
+
+	// ErrInvalidRefreshWindow is returned when a refresh-ahead cache is
+	// created with a window that is not positive.
+	ErrInvalidRefreshWindow = errors.New("Must provide a positive refresh window")
+
+	// ErrNoRefresh is returned when a refresh-ahead cache is created
+	// without a refresh function.
+	ErrNoRefresh = errors.New("Must provide a refresh function")
+
+	// ErrInvalidJanitorInterval is returned when a cache is created with a
+	// janitor interval that is not positive.
+	ErrInvalidJanitorInterval = errors.New("Must provide a positive janitor interval")
+
+	// ErrInvalidEvictBuffer is returned when a cache is created with an
+	// async evict buffer that is not positive.
+	ErrInvalidEvictBuffer = errors.New("Must provide a positive async evict buffer")
+)
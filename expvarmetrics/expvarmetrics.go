@@ -0,0 +1,51 @@
+// Package expvarmetrics publishes the metrics of an lru cache through the
+// standard expvar package.
+package expvarmetrics
+
+import "expvar"
+
+// Metrics implements lru.Metrics by updating an expvar.Map holding the
+// "hits", "misses", "evictions" and "size" of a cache.
+type Metrics struct {
+	hits      expvar.Int
+	misses    expvar.Int
+	evictions expvar.Int
+	size      expvar.Int
+	vars      *expvar.Map
+}
+
+// New creates a Metrics published under the given expvar name. Like
+// expvar.Publish, it panics if the name is already in use.
+func New(name string) *Metrics {
+	m := &Metrics{vars: expvar.NewMap(name)}
+	m.vars.Set("hits", &m.hits)
+	m.vars.Set("misses", &m.misses)
+	m.vars.Set("evictions", &m.evictions)
+	m.vars.Set("size", &m.size)
+	return m
+}
+
+// IncHits counts a hit.
+func (m *Metrics) IncHits() {
+	m.hits.Add(1)
+}
+
+// IncMisses counts a miss.
+func (m *Metrics) IncMisses() {
+	m.misses.Add(1)
+}
+
+// IncEvictions counts an eviction.
+func (m *Metrics) IncEvictions() {
+	m.evictions.Add(1)
+}
+
+// SetSize records the number of entries in the cache.
+func (m *Metrics) SetSize(size int) {
+	m.size.Set(int64(size))
+}
+
+// Map returns the published expvar.Map.
+func (m *Metrics) Map() *expvar.Map {
+	return m.vars
+}
@@ -0,0 +1,40 @@
+package expvarmetrics
+
+import (
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	lru "github.com/iocn-io/golang-lru"
+)
+
+// runs makes the expvar names published by repeated test runs, such as with
+// -count, unique, since expvar names cannot be reused
+var runs atomic.Int64
+
+func TestMetrics(t *testing.T) {
+	name := fmt.Sprintf("lru_%s_%d", t.Name(), runs.Add(1))
+	m := New(name)
+	l, err := lru.NewWithOptions(lru.WithSize(2), lru.WithMetrics(m))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	l.Get(3)
+	l.Get(1)
+	l.Remove(3)
+
+	want := map[string]int64{"hits": 1, "misses": 1, "evictions": 1, "size": 1}
+	for name, value := range want {
+		if v := m.Map().Get(name).(*expvar.Int).Value(); v != value {
+			t.Fatalf("bad %s: %v", name, v)
+		}
+	}
+	if expvar.Get(name) != m.Map() {
+		t.Fatalf("metrics should be published")
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/iocn-io/golang-lru
 
-go 1.12
+go 1.23
@@ -1,16 +1,98 @@
 package lru
 
 import (
+	"context"
+	"io"
+	"iter"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/iocn-io/golang-lru/simplelru"
 )
 
+// NoExpire can be passed as the TTL of AddEx and similar methods to add an
+// entry that never expires, even in a cache with a default expiry. Like
+// simplelru.NoExpire, it cannot result from subtracting times, and every
+// other non-positive TTL falls back to the default.
+const NoExpire = simplelru.NoExpire
+
+// Stats holds the hit, miss and eviction counters of a cache.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Pinned is the number of entries currently pinned
+	Pinned int
+}
+
 // Cache is a thread-safe fixed size LRU cache.
 type Cache struct {
-	lru  simplelru.LRUCache
+	// The counters are accessed atomically and kept first in the struct
+	// so they are 64-bit aligned on 32-bit platforms.
+	hits      uint64
+	misses    uint64
+	evictions uint64
+	// droppedEvictions counts evictions discarded under AsyncEvictDrop
+	droppedEvictions uint64
+
+	lru  *simplelru.LRU
 	lock sync.RWMutex
+
+	stopJanitor chan struct{}
+	janitorDone chan struct{}
+	closeOnce   sync.Once
+
+	// calls tracks in-flight GetOrCompute computations, guarded by lock
+	calls map[interface{}]*computeCall
+
+	// refresh reloads entries read within refreshWindow of their expiry;
+	// refreshing tracks the keys being reloaded, guarded by lock
+	refresh       func(key interface{}) (interface{}, error)
+	refreshWindow time.Duration
+	refreshing    map[interface{}]struct{}
+
+	// clock is the time source shared with lru, nil for the system clock
+	clock simplelru.Clock
+
+	// metrics is told about the events counted in Stats, nil for none
+	metrics Metrics
+
+	// clone copies values handed out by reads, and values stored by adds if
+	// cloneOnAdd is set; nil for no copying
+	clone      CloneFunc
+	cloneOnAdd bool
+
+	// evictQueue feeds evictions to onEvicted from a separate goroutine,
+	// nil unless WithAsyncEvict is used. evictClosed is set by Close,
+	// after which evictions are reported directly; guarded by lock.
+	onEvicted   func(key interface{}, value interface{})
+	evictQueue  chan evictedEntry
+	evictPolicy AsyncEvictPolicy
+	evictDone   chan struct{}
+	evictClosed bool
+
+	// onReject is told about adds refused because the cache is frozen
+	onReject simplelru.RejectCallback
+
+	// frozen is set by Freeze. It is read atomically so reads can choose
+	// the shared lock, and only changed with lock held.
+	frozen int32
+
+	// lruEvictions is the value of lru.Evictions already counted in
+	// evictions, guarded by lock
+	lruEvictions uint64
+}
+
+// CloneFunc returns a deep copy of a cached value
+type CloneFunc func(value interface{}) interface{}
+
+// computeCall is an in-flight or completed GetOrCompute computation
+type computeCall struct {
+	done  chan struct{}
+	value interface{}
+	ttl   time.Duration
+	err   error
 }
 
 // New creates an LRU of the given size.
@@ -21,156 +103,1313 @@ func New(size int) (*Cache, error) {
 // NewWithEvict constructs a fixed size cache with the given eviction
 // callback.
 func NewWithEvict(size int, onEvicted func(key interface{}, value interface{})) (*Cache, error) {
-	lru, err := simplelru.NewLRU(size, simplelru.EvictCallback(onEvicted))
-	if err != nil {
-		return nil, err
-	}
-	c := &Cache{
-		lru: lru,
-	}
-	return c, nil
+	return NewWithOptions(WithSize(size), WithEvict(onEvicted))
 }
 
+// NewWithExpire constructs a fixed size cache whose entries expire after
+// the given duration.
 func NewWithExpire(size int, expire time.Duration) (*Cache, error) {
-	lru, err := simplelru.NewLRUWithExpire(size, expire, nil)
+	return NewWithOptions(WithSize(size), WithTTL(expire))
+}
+
+// NewWithEvictExpire constructs a fixed size cache with the given expiry
+// and eviction callback.
+func NewWithEvictExpire(size int, expire time.Duration, onEvicted func(key interface{}, value interface{})) (*Cache, error) {
+	return NewWithOptions(WithSize(size), WithTTL(expire), WithEvict(onEvicted))
+}
+
+// NewWithSlidingExpire constructs a fixed size cache whose entries expire
+// after the given duration without being read. Each successful Get refreshes
+// the entry's expiry.
+func NewWithSlidingExpire(size int, expire time.Duration, onEvicted func(key interface{}, value interface{})) (*Cache, error) {
+	return NewWithOptions(WithSize(size), WithSlidingTTL(expire), WithEvict(onEvicted))
+}
+
+// NewWithExpireCallback constructs a fixed size cache with the given expiry,
+// eviction callback and expiration callback. onExpired is invoked instead of
+// onEvicted when an entry is removed because it expired.
+func NewWithExpireCallback(size int, expire time.Duration, onEvicted, onExpired func(key interface{}, value interface{})) (*Cache, error) {
+	return NewWithOptions(WithSize(size), WithTTL(expire), WithEvict(onEvicted), WithExpireCallback(onExpired))
+}
+
+// NewWithReason constructs a fixed size cache with the given expiry whose
+// eviction callback is told why each entry was removed.
+func NewWithReason(size int, expire time.Duration, onEvicted simplelru.EvictCallbackWithReason) (*Cache, error) {
+	return NewWithOptions(WithSize(size), WithTTL(expire), WithEvictReason(onEvicted))
+}
+
+// NewWithMetaCallback constructs a fixed size cache with the given expiry
+// whose eviction callback receives each entry's metadata.
+func NewWithMetaCallback(size int, expire time.Duration, onEvicted simplelru.EvictCallbackWithMeta) (*Cache, error) {
+	return NewWithOptions(WithSize(size), WithTTL(expire), WithEvictMeta(onEvicted))
+}
+
+// NewWithRefreshAhead constructs a fixed size cache whose entries expire
+// after the given duration and are refreshed ahead of time. A Get on an
+// entry within window of its expiry returns the current value immediately
+// and calls refresh in the background to replace it, restarting its expiry.
+// At most one refresh per key runs at a time, and failed refreshes leave
+// the entry to expire as usual.
+func NewWithRefreshAhead(size int, expire, window time.Duration, refresh func(key interface{}) (interface{}, error)) (*Cache, error) {
+	if window <= 0 {
+		return nil, ErrInvalidRefreshWindow
+	}
+	if refresh == nil {
+		return nil, ErrNoRefresh
+	}
+	c, err := NewWithExpire(size, expire)
 	if err != nil {
 		return nil, err
 	}
-	c := &Cache{
-		lru: lru,
-	}
+	c.refresh = refresh
+	c.refreshWindow = window
+	c.refreshing = make(map[interface{}]struct{})
 	return c, nil
 }
 
-func NewWithEvictExpire(size int, expire time.Duration, onEvicted func(key interface{}, value interface{})) (*Cache, error) {
-	lru, err := simplelru.NewLRUWithExpire(size, expire, onEvicted)
-	if err != nil {
-		return nil, err
+// NewWithJanitor constructs a fixed size cache with the given expiry and
+// eviction callback, and starts a background goroutine that removes expired
+// entries every interval. Close must be called to stop the goroutine.
+func NewWithJanitor(size int, expire, interval time.Duration, onEvicted func(key interface{}, value interface{})) (*Cache, error) {
+	return NewWithOptions(WithSize(size), WithTTL(expire), WithEvict(onEvicted), WithJanitor(interval))
+}
+
+// runJanitor removes expired entries every interval until Close is called.
+func (c *Cache) runJanitor(interval time.Duration) {
+	defer close(c.janitorDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.lock.Lock()
+			if !c.IsFrozen() {
+				c.lru.RemoveExpired()
+				c.reportSize()
+			}
+			c.lock.Unlock()
+		case <-c.stopJanitor:
+			return
+		}
 	}
-	c := &Cache{
-		lru: lru,
+}
+
+// Freeze makes the cache read-only until Unfreeze is called, for serving a
+// precomputed set of entries. While frozen no method changes which entries
+// are cached, their values, recency or expiry. Reads such as Get, GetMany,
+// Peek, Contains and Keys only take a shared lock, so they run in parallel,
+// and behave like GetQuiet: recency is not updated and expired entries are
+// neither returned nor removed. Methods that add, update, remove or pin
+// entries or change their TTL are no-ops that report nothing changed, while
+// the lookup half of GetOrAdd, AddIfAbsent, ContainsOrAdd, PeekOrAdd and
+// Swap still reports the current value. TryResize and Load return
+// ErrFrozen, as do GetOrCompute, GetOrLoad and GetMultiOrLoad for keys that
+// are not cached, without calling the loader. The janitor pauses. Every
+// value an add would have stored is reported to the callback set with
+// SetOnReject. Do still runs its function on the underlying LRU.
+func (c *Cache) Freeze() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	atomic.StoreInt32(&c.frozen, 1)
+}
+
+// SetOnReject registers a callback invoked for every entry an add refuses
+// because the cache is frozen, with simplelru.RejectFrozen, so it can be
+// routed elsewhere. This covers every add, from Add and AddMany to
+// AddWithMeta, AddNegative, which reports a nil value, and Swap, as well
+// as the missing keys of GetOrAdd, ContainsOrAdd, PeekOrAdd and
+// AddIfAbsent. Update and the compare-and-swap methods only report false.
+// It is called with the cache locked and must not use it. A nil callback
+// disables it.
+func (c *Cache) SetOnReject(onReject simplelru.RejectCallback) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onReject = onReject
+}
+
+// reject reports an add refused while frozen. The caller must hold the
+// lock.
+func (c *Cache) reject(key, value interface{}) {
+	if c.onReject != nil {
+		c.onReject(key, value, simplelru.RejectFrozen)
 	}
-	return c, nil
+}
+
+// Unfreeze makes a frozen cache writable again.
+func (c *Cache) Unfreeze() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	atomic.StoreInt32(&c.frozen, 0)
+}
+
+// IsFrozen reports whether the cache has been made read-only by Freeze.
+func (c *Cache) IsFrozen() bool {
+	return atomic.LoadInt32(&c.frozen) != 0
+}
+
+// Close stops the background janitor, if one was started, and waits for it
+// to exit. With WithAsyncEvict it also waits for the queued evictions to be
+// reported; later evictions call the callback directly, as without the
+// option. It is safe to call Close more than once.
+func (c *Cache) Close() {
+	if c.stopJanitor == nil && c.evictQueue == nil {
+		return
+	}
+	c.closeOnce.Do(func() {
+		if c.stopJanitor != nil {
+			close(c.stopJanitor)
+			<-c.janitorDone
+		}
+		if c.evictQueue != nil {
+			c.closeEvictions()
+		}
+	})
 }
 
 // Purge is used to completely clear the cache.
 func (c *Cache) Purge() {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return
+	}
 	c.lru.Purge()
+	c.reportSize()
+}
+
+// Drain removes every entry from the cache and returns the unexpired ones,
+// from oldest to newest, as a single operation. Unlike Purge, no callbacks
+// are fired, since the caller takes ownership of the entries, which makes it
+// suitable for handing off the contents on shutdown.
+func (c *Cache) Drain() []simplelru.EntryView {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return nil
+	}
+	views := c.lru.Drain()
+	c.reportSize()
+	return views
 }
 
 // Add adds a value to the cache. Returns true if an eviction occurred.
 func (c *Cache) Add(key, value interface{}) (evicted bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	evicted = c.lru.Add(key, value)
+	if c.IsFrozen() {
+		c.reject(key, value)
+		return false
+	}
+	evicted = c.lru.Add(key, c.cloneAdded(value))
+	c.recordEvictions()
 	return evicted
 }
 
+// AddMany adds several values to the cache under a single lock, returning
+// the number of evictions that occurred. Entries are added in map iteration
+// order, which Go does not define; use AddManyOrdered when the resulting
+// recency order matters.
+func (c *Cache) AddMany(entries map[interface{}]interface{}) (evicted int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		for key, value := range entries {
+			c.reject(key, value)
+		}
+		return 0
+	}
+	for key, value := range entries {
+		c.lru.Add(key, c.cloneAdded(value))
+	}
+	return c.recordEvictions()
+}
+
+// AddManyOrdered adds keys[i] with values[i] to the cache in order under a
+// single lock, returning the number of evictions that occurred. It panics if
+// keys and values differ in length.
+func (c *Cache) AddManyOrdered(keys []interface{}, values []interface{}) (evicted int) {
+	if len(keys) != len(values) {
+		panic("lru: AddManyOrdered called with mismatched keys and values")
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		for i, key := range keys {
+			c.reject(key, values[i])
+		}
+		return 0
+	}
+	for i, key := range keys {
+		c.lru.Add(key, c.cloneAdded(values[i]))
+	}
+	return c.recordEvictions()
+}
+
+// AddEx adds a value to the cache with a per-key expiry, overriding the
+// default. A positive expire is used as the TTL, NoExpire adds an entry that
+// never expires, and other non-positive values fall back to the default.
+// Returns true if an eviction occurred.
 func (c *Cache) AddEx(key, value interface{}, expire time.Duration) (evicted bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	evicted = c.lru.AddEx(key, value, expire)
+	if c.IsFrozen() {
+		c.reject(key, value)
+		return false
+	}
+	evicted = c.lru.AddEx(key, c.cloneAdded(value), expire)
+	c.recordEvictions()
+	return evicted
+}
+
+// AddUntil adds a value to the cache that expires at the given deadline,
+// or never if deadline is zero. Returns true if an eviction occurred.
+func (c *Cache) AddUntil(key, value interface{}, deadline time.Time) (evicted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		c.reject(key, value)
+		return false
+	}
+	evicted = c.lru.AddUntil(key, c.cloneAdded(value), deadline)
+	c.recordEvictions()
+	return evicted
+}
+
+// Update replaces the value of an existing key without updating its
+// recent-ness or expiry. Returns false if the key is not in the cache.
+func (c *Cache) Update(key, value interface{}) (ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return false
+	}
+	ok = c.lru.Update(key, c.cloneAdded(value))
+	return ok
+}
+
+// GetWithVersion looks up a key's value from the cache along with its
+// version, which changes every time the value does.
+func (c *Cache) GetWithVersion(key interface{}) (value interface{}, version uint64, ok bool) {
+	if c.IsFrozen() {
+		info, ok := c.inspect(key)
+		return c.cloneValue(info.Value), info.Version, ok
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	value, version, ok = c.lru.GetWithVersion(key)
+	c.recordAccess(ok)
+	return c.cloneValue(value), version, ok
+}
+
+// UpdateIfVersion replaces the value of an existing key only if its version
+// is still the one returned by an earlier GetWithVersion, so concurrent read-
+// modify-write cycles cannot overwrite each other's changes. Returns false
+// if the key is missing, expired, or was changed in between.
+func (c *Cache) UpdateIfVersion(key, value interface{}, expectedVersion uint64) (ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return false
+	}
+	return c.lru.UpdateIfVersion(key, c.cloneAdded(value), expectedVersion)
+}
+
+// CompareAndSwap replaces the value of an existing key with new if its
+// current value equals old, as a single atomic operation. The key's
+// recent-ness is updated only if the swap happened. Values are compared
+// with ==, which panics if they are not comparable; use CompareAndSwapFunc
+// for those. Returns false if the key is missing, expired, or holds a
+// different value.
+func (c *Cache) CompareAndSwap(key, old, new interface{}) (swapped bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return false
+	}
+	return c.lru.CompareAndSwap(key, old, c.cloneAdded(new))
+}
+
+// CompareAndSwapFunc is like CompareAndSwap, but compares the current value
+// with old using equal. equal is called with the cache locked and must not
+// call back into the cache.
+func (c *Cache) CompareAndSwapFunc(key, old, new interface{}, equal func(a, b interface{}) bool) (swapped bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return false
+	}
+	return c.lru.CompareAndSwapFunc(key, old, c.cloneAdded(new), equal)
+}
+
+// Increment atomically adds delta to the int64 value of an existing key,
+// updating its recent-ness, and returns the new value. Returns false,
+// without modifying anything, if the key is missing, expired, or does not
+// hold an int64.
+func (c *Cache) Increment(key interface{}, delta int64) (new int64, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return 0, false
+	}
+	return c.lru.Increment(key, delta)
+}
+
+// Decrement atomically subtracts delta from the int64 value of an existing
+// key, like Increment.
+func (c *Cache) Decrement(key interface{}, delta int64) (new int64, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return 0, false
+	}
+	return c.lru.Decrement(key, delta)
+}
+
+// AddWithMeta adds a value with a per-key expiry and attaches opaque
+// metadata to it. Returns true if an eviction occurred.
+func (c *Cache) AddWithMeta(key, value, meta interface{}, expire time.Duration) (evicted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		c.reject(key, value)
+		return false
+	}
+	evicted = c.lru.AddWithMeta(key, c.cloneAdded(value), meta, expire)
+	c.recordEvictions()
 	return evicted
 }
 
+// GetMeta returns the metadata attached to a key without updating its
+// recent-ness.
+func (c *Cache) GetMeta(key interface{}) (meta interface{}, ok bool) {
+	if c.IsFrozen() {
+		info, ok := c.inspect(key)
+		return info.Meta, ok
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.GetMeta(key)
+}
+
+// AddNegative caches the absence of a key with the given TTL. Returns true
+// if an eviction occurred.
+func (c *Cache) AddNegative(key interface{}, negTTL time.Duration) (evicted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		c.reject(key, nil)
+		return false
+	}
+	evicted = c.lru.AddNegative(key, negTTL)
+	c.recordEvictions()
+	return evicted
+}
+
+// GetNegative looks up a key in the cache and reports whether it was cached
+// as absent via AddNegative. ok is false if the key is not in the cache.
+func (c *Cache) GetNegative(key interface{}) (isNegative, ok bool) {
+	if c.IsFrozen() {
+		info, ok := c.inspect(key)
+		return info.Negative, ok
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	isNegative, ok = c.lru.GetNegative(key)
+	c.recordAccess(ok)
+	return isNegative, ok
+}
+
 // Get looks up a key's value from the cache.
 func (c *Cache) Get(key interface{}) (value interface{}, ok bool) {
+	if c.IsFrozen() {
+		return c.GetQuiet(key)
+	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	value, ok = c.lru.Get(key)
-	return value, ok
+	if c.refresh == nil {
+		value, ok = c.lru.Get(key)
+		c.recordAccess(ok)
+		return c.cloneValue(value), ok
+	}
+	var expireAt time.Time
+	value, expireAt, ok = c.lru.GetWithExpire(key)
+	c.recordAccess(ok)
+	if ok && !expireAt.IsZero() && expireAt.Sub(c.now()) <= c.refreshWindow {
+		c.refreshAhead(key)
+	}
+	return c.cloneValue(value), ok
 }
 
-// Contains checks if a key is in the cache, without updating the
-// recent-ness or deleting it for being stale.
-func (c *Cache) Contains(key interface{}) bool {
+// SetClock replaces the time source used for expiry, which lets tests
+// advance time deterministically. A nil clock restores the real clock.
+func (c *Cache) SetClock(clock simplelru.Clock) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.clock = clock
+	c.lru.SetClock(clock)
+}
+
+// SetOnAdd registers a callback invoked whenever an add inserts a new key
+// (updated is false) or overwrites an existing one (updated is true). The
+// callback runs while the cache lock is held, so it must not call back into
+// the cache.
+func (c *Cache) SetOnAdd(onAdd simplelru.AddCallback) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lru.SetOnAdd(onAdd)
+}
+
+// Do calls f with the underlying non-thread-safe LRU while holding the
+// write lock, so several operations can be performed atomically. f must not
+// keep the LRU after it returns, and must not call any method of the Cache,
+// which would deadlock. Operations performed by f are not counted in Stats.
+func (c *Cache) Do(f func(lru *simplelru.LRU)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	f(c.lru)
+	c.lruEvictions = c.lru.Evictions()
+	c.reportSize()
+}
+
+// cloneValue returns a copy of a value being returned to the caller, if a
+// CloneFunc is configured. nil values are returned as they are.
+func (c *Cache) cloneValue(value interface{}) interface{} {
+	if c.clone == nil || value == nil {
+		return value
+	}
+	return c.clone(value)
+}
+
+// cloneAdded returns a copy of a value being stored in the cache, if it was
+// configured to clone on add.
+func (c *Cache) cloneAdded(value interface{}) interface{} {
+	if !c.cloneOnAdd {
+		return value
+	}
+	return c.cloneValue(value)
+}
+
+// now returns the current time according to the cache's clock. The caller
+// must hold the lock.
+func (c *Cache) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+// refreshAhead starts a background refresh of key unless one is already
+// running. The caller must hold the lock.
+func (c *Cache) refreshAhead(key interface{}) {
+	if _, ok := c.refreshing[key]; ok {
+		return
+	}
+	c.refreshing[key] = struct{}{}
+	go func() {
+		value, err := c.refresh(key)
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		delete(c.refreshing, key)
+		if err == nil && !c.IsFrozen() {
+			c.lru.Renew(key, value)
+		}
+	}()
+}
+
+// GetMany looks up several keys' values from the cache under a single lock,
+// updating the recent-ness of each key found. Keys that are not found are
+// returned in missing, in the order they were requested.
+func (c *Cache) GetMany(keys []interface{}) (values map[interface{}]interface{}, missing []interface{}) {
+	get := c.lru.Get
+	if c.IsFrozen() {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		get = c.lru.GetQuiet
+	} else {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+	}
+	values = make(map[interface{}]interface{}, len(keys))
+	for _, key := range keys {
+		value, ok := get(key)
+		c.recordAccess(ok)
+		if ok {
+			values[key] = c.cloneValue(value)
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	return values, missing
+}
+
+// ContainsMany checks which of several keys are in the cache under a single
+// read lock, without updating their recent-ness. Like GetQuiet it leaves the
+// cache untouched, so expired keys are reported as missing but not removed.
+func (c *Cache) ContainsMany(keys []interface{}) map[interface{}]bool {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
+	found := make(map[interface{}]bool, len(keys))
+	for _, key := range keys {
+		_, found[key] = c.lru.GetQuiet(key)
+	}
+	return found
+}
+
+// GetWithExpire looks up a key's value from the cache, also returning the
+// time at which it expires. The returned time is zero if the key has no
+// expiry.
+func (c *Cache) GetWithExpire(key interface{}) (value interface{}, expireAt time.Time, ok bool) {
+	if c.IsFrozen() {
+		info, ok := c.inspect(key)
+		return c.cloneValue(info.Value), info.ExpireAt, ok
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	value, expireAt, ok = c.lru.GetWithExpire(key)
+	c.recordAccess(ok)
+	return c.cloneValue(value), expireAt, ok
+}
+
+// GetAndRefresh looks up a key's value from the cache, updating its
+// recent-ness, and restarts its expiry to expire newTTL from now, falling
+// back to the cache's default expiry if newTTL is not positive. Missing or
+// expired keys are not inserted.
+func (c *Cache) GetAndRefresh(key interface{}, newTTL time.Duration) (value interface{}, ok bool) {
+	if c.IsFrozen() {
+		return c.GetQuiet(key)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	value, ok = c.lru.GetAndRefresh(key, newTTL)
+	c.recordAccess(ok)
+	return c.cloneValue(value), ok
+}
+
+// Touch marks a key as recently used without reading its value, refreshing
+// its expiry if the cache uses sliding expiration. Returns whether the key
+// was present and unexpired.
+func (c *Cache) Touch(key interface{}) bool {
+	if c.IsFrozen() {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		_, ok := c.lru.Inspect(key)
+		return ok
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Touch(key)
+}
+
+// Pin exempts an existing key from capacity eviction, so adds evict the
+// oldest unpinned entry instead, and RemoveOldest skips it. If every entry
+// is pinned, adds still succeed and the cache goes over its size until
+// entries are unpinned or removed. Pinned entries still expire and can still
+// be removed by key. Returns
+// false if the key is not in the cache or has expired.
+func (c *Cache) Pin(key interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return false
+	}
+	return c.lru.Pin(key)
+}
+
+// Unpin makes a pinned key subject to capacity eviction again. Returns
+// false if the key is not in the cache or has expired.
+func (c *Cache) Unpin(key interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return false
+	}
+	return c.lru.Unpin(key)
+}
+
+// SetTTL restarts the expiry of an existing key to expire ttl from now,
+// without touching its value or recent-ness, such as to extend a lease.
+// NoExpire makes the entry never expire. Returns false if the key is not in
+// the cache or has expired.
+func (c *Cache) SetTTL(key interface{}, ttl time.Duration) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return false
+	}
+	return c.lru.SetTTL(key, ttl)
+}
+
+// Contains checks if a key is in the cache, without updating the
+// recent-ness. An expired key is removed from the cache.
+func (c *Cache) Contains(key interface{}) bool {
+	if c.IsFrozen() {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		_, ok := c.lru.GetQuiet(key)
+		return ok
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	containKey := c.lru.Contains(key)
 	return containKey
 }
 
+// ContainsWithTTL checks if a key is in the cache, without updating the
+// recent-ness, and reports how long until it expires. remaining is -1 for
+// entries that never expire.
+func (c *Cache) ContainsWithTTL(key interface{}) (exists bool, remaining time.Duration) {
+	if c.IsFrozen() {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		info, ok := c.lru.Inspect(key)
+		switch {
+		case !ok:
+			return false, 0
+		case info.ExpireAt.IsZero():
+			return true, -1
+		}
+		return true, info.ExpireAt.Sub(c.now())
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.ContainsWithTTL(key)
+}
+
 // Peek returns the key value (or undefined if not found) without updating
-// the "recently used"-ness of the key.
+// the "recently used"-ness of the key. An expired key is removed from the
+// cache.
 func (c *Cache) Peek(key interface{}) (value interface{}, ok bool) {
+	if c.IsFrozen() {
+		return c.GetQuiet(key)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	value, ok = c.lru.Peek(key)
+	c.recordAccess(ok)
+	return c.cloneValue(value), ok
+}
+
+// PeekWithAccess is like Peek, but also returns when the key was last
+// added or, with WithAccessTracking or a sliding expiry, read.
+func (c *Cache) PeekWithAccess(key interface{}) (value interface{}, lastAccess time.Time, ok bool) {
+	if c.IsFrozen() {
+		info, ok := c.inspect(key)
+		return c.cloneValue(info.Value), info.LastAccess, ok
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	value, lastAccess, ok = c.lru.PeekWithAccess(key)
+	c.recordAccess(ok)
+	return c.cloneValue(value), lastAccess, ok
+}
+
+// GetQuiet returns the key value if it is present and unexpired without
+// modifying the cache in any way. Unlike Peek an expired key is not removed,
+// so GetQuiet only takes a read lock.
+func (c *Cache) GetQuiet(key interface{}) (value interface{}, ok bool) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
-	value, ok = c.lru.Peek(key)
-	return value, ok
+	value, ok = c.lru.GetQuiet(key)
+	c.recordAccess(ok)
+	return c.cloneValue(value), ok
+}
+
+// inspect looks up a key of a frozen cache under the read lock, counting a
+// hit or a miss.
+func (c *Cache) inspect(key interface{}) (info simplelru.EntryInfo, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	info, ok = c.lru.Inspect(key)
+	c.recordAccess(ok)
+	return info, ok
+}
+
+// PeekRaw returns the stored value of a key even if it has expired, along
+// with whether it has, without modifying the cache. It is meant for
+// monitoring how long expired entries linger before they are removed.
+func (c *Cache) PeekRaw(key interface{}) (value interface{}, expired bool, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	value, expired, ok = c.lru.PeekRaw(key)
+	return c.cloneValue(value), expired, ok
+}
+
+// GetOrAdd looks up a key's value from the cache, updating its recent-ness,
+// and if it is not present adds the given value. The returned actual value is
+// the existing value if loaded is true, otherwise the given value. This
+// mirrors the semantics of sync.Map's LoadOrStore.
+func (c *Cache) GetOrAdd(key, value interface{}) (actual interface{}, loaded bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	get := c.lru.Get
+	if c.IsFrozen() {
+		get = c.lru.GetQuiet
+	}
+	if actual, ok := get(key); ok {
+		c.recordAccess(true)
+		return c.cloneValue(actual), true
+	}
+	c.recordAccess(false)
+	if c.IsFrozen() {
+		c.reject(key, value)
+		return value, false
+	}
+	c.lru.Add(key, c.cloneAdded(value))
+	c.recordEvictions()
+	return value, false
+}
+
+// AddIfAbsent adds a value with the given TTL only if the key is not in the
+// cache, treating an expired entry as absent and overwriting it. It returns
+// the value now associated with the key and whether this call inserted it.
+// An existing entry's recent-ness is not updated. A TTL that is not
+// positive falls back to the cache's default expiry.
+func (c *Cache) AddIfAbsent(key, value interface{}, ttl time.Duration) (actual interface{}, inserted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.IsFrozen() {
+		actual, ok := c.lru.GetQuiet(key)
+		if !ok {
+			c.reject(key, value)
+		}
+		return c.cloneValue(actual), false
+	}
+	if actual, ok := c.lru.Peek(key); ok {
+		return c.cloneValue(actual), false
+	}
+	c.lru.AddEx(key, c.cloneAdded(value), ttl)
+	c.recordEvictions()
+	return value, true
+}
+
+// GetOrCompute looks up a key's value from the cache, and if it is not
+// present calls compute to produce it. Concurrent callers missing the same
+// key share a single call to compute, waiting for its result or until their
+// own ctx is done. compute is passed the ctx of the caller that started it.
+// A successful result is added to the cache; errors are returned to every
+// waiting caller but are not cached. If compute panics, the panic goes on
+// in the caller that started it and the others get ErrComputePanicked.
+func (c *Cache) GetOrCompute(ctx context.Context, key interface{}, compute func(context.Context) (interface{}, error)) (interface{}, error) {
+	return c.getOrCompute(ctx, key, func(ctx context.Context) (interface{}, time.Duration, error) {
+		value, err := compute(ctx)
+		return value, 0, err
+	})
+}
+
+// GetOrLoad looks up a key's value from the cache, and if it is missing or
+// expired calls loader to produce the value and its TTL, which are then
+// stored and returned. A TTL that is not positive falls back to the cache's
+// default expiry. On loader error nothing is cached and the error is
+// returned. Like GetOrCompute, concurrent misses share a single load.
+func (c *Cache) GetOrLoad(key interface{}, loader func(key interface{}) (interface{}, time.Duration, error)) (interface{}, error) {
+	return c.getOrCompute(context.Background(), key, func(context.Context) (interface{}, time.Duration, error) {
+		return loader(key)
+	})
+}
+
+// GetMultiOrLoad looks up several keys' values from the cache, updating the
+// recent-ness of each key found, and calls loader once with the keys that
+// are missing or expired, in the order they were requested. The values
+// loader returns for those keys are added to the cache and merged into
+// values; keys it leaves out are reported in missing. Other keys in its
+// result are ignored. On loader error nothing is cached and the error is
+// returned along with the values that were already cached. Unlike
+// GetOrLoad, concurrent misses are not coalesced, and loader is called
+// without holding the lock.
+func (c *Cache) GetMultiOrLoad(keys []interface{}, loader func(missing []interface{}) (map[interface{}]interface{}, error)) (values map[interface{}]interface{}, missing []interface{}, err error) {
+	values, missing = c.GetMany(keys)
+	if len(missing) == 0 {
+		return values, nil, nil
+	}
+
+	// Ask for each missing key once, even if it was requested twice
+	seen := make(map[interface{}]struct{}, len(missing))
+	unique := missing[:0]
+	for _, key := range missing {
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			unique = append(unique, key)
+		}
+	}
+	missing = unique
+	if c.IsFrozen() {
+		return values, missing, ErrFrozen
+	}
+
+	loaded, err := loader(missing)
+	if err != nil {
+		return values, missing, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return values, missing, ErrFrozen
+	}
+	var stillMissing []interface{}
+	for _, key := range missing {
+		value, ok := loaded[key]
+		if !ok {
+			stillMissing = append(stillMissing, key)
+			continue
+		}
+		c.lru.Add(key, c.cloneAdded(value))
+		values[key] = c.cloneValue(value)
+	}
+	c.recordEvictions()
+	return values, stillMissing, nil
+}
+
+// getOrCompute implements GetOrCompute and GetOrLoad, storing a successful
+// result with the TTL returned by compute.
+func (c *Cache) getOrCompute(ctx context.Context, key interface{}, compute func(context.Context) (interface{}, time.Duration, error)) (interface{}, error) {
+	if c.IsFrozen() {
+		if value, ok := c.GetQuiet(key); ok {
+			return value, nil
+		}
+		return nil, ErrFrozen
+	}
+	c.lock.Lock()
+	if value, ok := c.lru.Get(key); ok {
+		c.recordAccess(true)
+		c.lock.Unlock()
+		return c.cloneValue(value), nil
+	}
+	c.recordAccess(false)
+
+	// Wait for a computation that is already in flight
+	if call, ok := c.calls[key]; ok {
+		c.lock.Unlock()
+		select {
+		case <-call.done:
+			return c.cloneValue(call.value), call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	call := &computeCall{done: make(chan struct{})}
+	if c.calls == nil {
+		c.calls = make(map[interface{}]*computeCall)
+	}
+	c.calls[key] = call
+	c.lock.Unlock()
+
+	// Release waiters even if compute panics
+	finished := false
+	defer func() {
+		if !finished {
+			call.err = ErrComputePanicked
+		}
+		c.lock.Lock()
+		delete(c.calls, key)
+		if call.err == nil && !c.IsFrozen() {
+			c.lru.AddEx(key, c.cloneAdded(call.value), call.ttl)
+			c.recordEvictions()
+		}
+		c.lock.Unlock()
+		close(call.done)
+	}()
+	call.value, call.ttl, call.err = compute(ctx)
+	finished = true
+	if call.err != nil {
+		call.value = nil
+	}
+	return c.cloneValue(call.value), call.err
 }
 
 // ContainsOrAdd checks if a key is in the cache without updating the
-// recent-ness or deleting it for being stale, and if not, adds the value.
+// recent-ness, and if not, adds the value.
+// An expired key is treated as absent and overwritten.
 // Returns whether found and whether an eviction occurred.
 func (c *Cache) ContainsOrAdd(key, value interface{}) (ok, evicted bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	if c.IsFrozen() {
+		if _, ok = c.lru.GetQuiet(key); !ok {
+			c.reject(key, value)
+		}
+		return ok, false
+	}
 	if c.lru.Contains(key) {
 		return true, false
 	}
-	evicted = c.lru.Add(key, value)
+	evicted = c.lru.Add(key, c.cloneAdded(value))
+	c.recordEvictions()
 	return false, evicted
 }
 
 // PeekOrAdd checks if a key is in the cache without updating the
-// recent-ness or deleting it for being stale, and if not, adds the value.
-// Returns whether found and whether an eviction occurred.
+// recent-ness, and if not, adds the value.
+// An expired key is treated as absent and overwritten.
+// Returns the previous value if found, whether found and whether an
+// eviction occurred.
 func (c *Cache) PeekOrAdd(key, value interface{}) (previous interface{}, ok, evicted bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	if c.IsFrozen() {
+		if previous, ok = c.lru.GetQuiet(key); !ok {
+			c.reject(key, value)
+		}
+		return c.cloneValue(previous), ok, false
+	}
 	previous, ok = c.lru.Peek(key)
 	if ok {
-		return previous, true, false
+		return c.cloneValue(previous), true, false
 	}
 
-	evicted = c.lru.Add(key, value)
+	evicted = c.lru.Add(key, c.cloneAdded(value))
+	c.recordEvictions()
 	return nil, false, evicted
 }
 
+// Swap sets the value for a key, updating its recent-ness, and returns the
+// previous value if there was an unexpired one. This mirrors the semantics
+// of sync.Map's Swap.
+func (c *Cache) Swap(key, value interface{}) (previous interface{}, loaded bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.IsFrozen() {
+		c.reject(key, value)
+		previous, loaded = c.lru.GetQuiet(key)
+		return c.cloneValue(previous), loaded
+	}
+	previous, loaded = c.lru.Peek(key)
+	c.lru.Add(key, c.cloneAdded(value))
+	c.recordEvictions()
+	return c.cloneValue(previous), loaded
+}
+
 // Remove removes the provided key from the cache.
 func (c *Cache) Remove(key interface{}) (present bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return false
+	}
 	present = c.lru.Remove(key)
+	c.reportSize()
 	return
 }
 
-// Resize changes the cache size.
-func (c *Cache) Resize(size int) (evicted int) {
+// GetDelete looks up a key's value and removes it from the cache in a
+// single operation, so only one caller can consume an entry.
+func (c *Cache) GetDelete(key interface{}) (value interface{}, ok bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	evicted = c.lru.Resize(size)
-	return evicted
+	if c.IsFrozen() {
+		return nil, false
+	}
+	value, ok = c.lru.GetDelete(key)
+	c.recordAccess(ok)
+	c.reportSize()
+	return c.cloneValue(value), ok
+}
+
+// RemoveFunc removes every unexpired entry for which pred returns true,
+// returning how many were removed. pred is called with the cache locked and
+// must not call back into the cache.
+func (c *Cache) RemoveFunc(pred func(key, value interface{}) bool) (removed int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return 0
+	}
+	removed = c.lru.RemoveFunc(pred)
+	c.reportSize()
+	return removed
+}
+
+// RemovePrefix removes every unexpired entry whose key is a string starting
+// with prefix, such as all "user:123:" keys, and returns how many were
+// removed. Keys of other types are never matched.
+func (c *Cache) RemovePrefix(prefix string) (removed int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return 0
+	}
+	removed = c.lru.RemovePrefix(prefix)
+	c.reportSize()
+	return removed
+}
+
+// Map calls f for every unexpired entry under a single lock, storing the
+// value it returns back in place without updating recent-ness, or removing
+// the entry if f returns keep as false. f is called with the cache locked
+// and must not call back into the cache.
+func (c *Cache) Map(f func(key interface{}, value interface{}) (newValue interface{}, keep bool)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return
+	}
+	c.lru.Map(func(key interface{}, value interface{}) (interface{}, bool) {
+		newValue, keep := f(key, c.cloneValue(value))
+		return c.cloneAdded(newValue), keep
+	})
+	c.reportSize()
 }
 
-// RemoveOldest removes the oldest item from the cache.
+// RemoveExpired removes all expired items from the cache, firing their
+// expire or evict callbacks, and returns how many were removed. It lets
+// callers sweep at a chosen moment instead of, or as well as, running a
+// janitor.
+func (c *Cache) RemoveExpired() (removed int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return 0
+	}
+	removed = c.lru.RemoveExpired()
+	c.reportSize()
+	return removed
+}
+
+// Resize changes the cache size, returning the number of entries evicted
+// and the previous size. A non-positive size leaves the cache unchanged.
+func (c *Cache) Resize(size int) (evicted, previous int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return 0, c.lru.Cap()
+	}
+	evicted, previous = c.lru.Resize(size)
+	c.recordEvictionCount(evicted)
+	return evicted, previous
+}
+
+// TryResize is like Resize, but returns an error instead of silently
+// ignoring a non-positive size.
+func (c *Cache) TryResize(size int) (evicted, previous int, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return 0, c.lru.Cap(), ErrFrozen
+	}
+	evicted, previous, err = c.lru.TryResize(size)
+	c.recordEvictionCount(evicted)
+	return evicted, previous, err
+}
+
+// RemoveOldest removes the oldest unpinned item from the cache.
 func (c *Cache) RemoveOldest() (key interface{}, value interface{}, ok bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return nil, nil, false
+	}
 	key, value, ok = c.lru.RemoveOldest()
-	return
+	c.reportSize()
+	return key, c.cloneValue(value), ok
+}
+
+// RemoveOldestN removes up to n of the oldest unpinned items from the
+// cache, returning how many were removed.
+func (c *Cache) RemoveOldestN(n int) (removed int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return 0
+	}
+	removed = c.lru.RemoveOldestN(n)
+	c.reportSize()
+	return removed
 }
 
 // GetOldest returns the oldest entry
 func (c *Cache) GetOldest() (key interface{}, value interface{}, ok bool) {
+	if c.IsFrozen() {
+		return c.PeekOldest()
+	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	key, value, ok = c.lru.GetOldest()
-	return
+	return key, c.cloneValue(value), ok
 }
 
-// Keys returns a slice of the keys in the cache, from oldest to newest.
-func (c *Cache) Keys() []interface{} {
+// PeekOldest returns the oldest unexpired entry without updating its
+// recent-ness or removing expired entries.
+func (c *Cache) PeekOldest() (key interface{}, value interface{}, ok bool) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
+	key, value, ok = c.lru.PeekOldest()
+	return key, c.cloneValue(value), ok
+}
+
+// GetNewest returns the most recently used unexpired entry.
+func (c *Cache) GetNewest() (key interface{}, value interface{}, ok bool) {
+	if c.IsFrozen() {
+		return c.PeekNewest()
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key, value, ok = c.lru.GetNewest()
+	return key, c.cloneValue(value), ok
+}
+
+// PeekNewest returns the most recently used unexpired entry without
+// modifying the cache.
+func (c *Cache) PeekNewest() (key interface{}, value interface{}, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	key, value, ok = c.lru.PeekNewest()
+	return key, c.cloneValue(value), ok
+}
+
+// Keys returns a slice of the keys in the cache, from oldest to newest.
+// Expired keys are removed.
+func (c *Cache) Keys() []interface{} {
+	if c.IsFrozen() {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		keys, _ := c.lru.KeysPage(0, c.lru.Len())
+		return keys
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	keys := c.lru.Keys()
 	return keys
 }
 
+// Range calls f for each unexpired entry in the cache, from oldest to
+// newest, without updating their recent-ness. Iteration stops early if f
+// returns false. f is called with the cache locked and must not call back
+// into the cache.
+func (c *Cache) Range(f func(key, value interface{}) bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	c.lru.Range(func(key, value interface{}) bool {
+		return f(key, c.cloneValue(value))
+	})
+}
+
+// All returns an iterator over the unexpired entries in the cache, from
+// oldest to newest, without updating their recent-ness. The cache is locked
+// for the duration of the loop, so the loop body must not call back into
+// the cache.
+func (c *Cache) All() iter.Seq2[interface{}, interface{}] {
+	return func(yield func(interface{}, interface{}) bool) {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		for k, v := range c.lru.All() {
+			if !yield(k, c.cloneValue(v)) {
+				return
+			}
+		}
+	}
+}
+
+// Backward is like All, but iterates from newest to oldest.
+func (c *Cache) Backward() iter.Seq2[interface{}, interface{}] {
+	return func(yield func(interface{}, interface{}) bool) {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		for k, v := range c.lru.Backward() {
+			if !yield(k, c.cloneValue(v)) {
+				return
+			}
+		}
+	}
+}
+
+// Clone returns an independent copy of the cache holding the unexpired
+// entries in the same order. Values are copied shallowly. A janitor, if
+// any, is not started for the copy.
+func (c *Cache) Clone() *Cache {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	lru := c.lru.Clone()
+	if c.evictQueue != nil {
+		// The queue belongs to c, so the copy reports its evictions directly
+		lru.SetOnEvict(c.onEvicted)
+	}
+	return &Cache{
+		lru:        lru,
+		clock:      c.clock,
+		clone:      c.clone,
+		cloneOnAdd: c.cloneOnAdd,
+	}
+}
+
+// Dump writes the unexpired entries of the cache to w using gob, from
+// oldest to newest. Concrete key and value types must be registered with
+// gob.Register.
+func (c *Cache) Dump(w io.Writer) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.Dump(w)
+}
+
+// Load reads entries written by Dump from r and adds them to the cache,
+// skipping entries that have since expired.
+func (c *Cache) Load(r io.Reader) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.IsFrozen() {
+		return ErrFrozen
+	}
+	err := c.lru.Load(r)
+	c.recordEvictions()
+	return err
+}
+
+// KeysPage returns up to limit keys starting at offset, from oldest to
+// newest, along with the total number of unexpired keys.
+func (c *Cache) KeysPage(offset, limit int) (keys []interface{}, total int) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.KeysPage(offset, limit)
+}
+
+// Values returns a slice of the values in the cache, from oldest to newest.
+func (c *Cache) Values() []interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	values := c.lru.Values()
+	for i, value := range values {
+		values[i] = c.cloneValue(value)
+	}
+	return values
+}
+
+// Entries returns a slice of the key/value pairs in the cache, from oldest
+// to newest.
+func (c *Cache) Entries() []simplelru.Entry {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	entries := c.lru.Entries()
+	for i := range entries {
+		entries[i].Value = c.cloneValue(entries[i].Value)
+	}
+	return entries
+}
+
+// Snapshot returns a copy of the unexpired entries in the cache with their
+// expiry times, from newest to oldest.
+func (c *Cache) Snapshot() []simplelru.EntryView {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	views := c.lru.Snapshot()
+	for i := range views {
+		views[i].Value = c.cloneValue(views[i].Value)
+	}
+	return views
+}
+
+// TTLStats returns a summary of the remaining time to live of the
+// unexpired entries. It never modifies the cache.
+func (c *Cache) TTLStats() simplelru.TTLStats {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.TTLStats()
+}
+
 // Len returns the number of items in the cache.
 func (c *Cache) Len() int {
 	c.lock.RLock()
@@ -178,3 +1417,98 @@ func (c *Cache) Len() int {
 	length := c.lru.Len()
 	return length
 }
+
+// Cap returns the maximum number of items the cache holds.
+func (c *Cache) Cap() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lru.Cap()
+}
+
+// LenValid returns the number of unexpired items in the cache.
+func (c *Cache) LenValid() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	length := c.lru.LenValid()
+	return length
+}
+
+// Stats returns a snapshot of the cache's hit, miss and eviction counters.
+// Get and Peek count towards hits and misses, and evictions caused by Add
+// or Resize count towards evictions. Pinned is the number of pinned
+// entries, which ResetStats leaves alone.
+func (c *Cache) Stats() Stats {
+	c.lock.RLock()
+	pinned := c.lru.PinnedLen()
+	c.lock.RUnlock()
+	return Stats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+		Pinned:    pinned,
+	}
+}
+
+// ResetStats zeroes the cache's hit, miss and eviction counters.
+func (c *Cache) ResetStats() {
+	atomic.StoreUint64(&c.hits, 0)
+	atomic.StoreUint64(&c.misses, 0)
+	atomic.StoreUint64(&c.evictions, 0)
+}
+
+// SetMetrics registers a collector that is told about hits, misses,
+// evictions and size changes as they happen, alongside the counters
+// returned by Stats. A nil collector disables it.
+func (c *Cache) SetMetrics(metrics Metrics) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.metrics = metrics
+	c.reportSize()
+}
+
+// recordAccess counts a lookup as a hit or a miss.
+func (c *Cache) recordAccess(hit bool) {
+	if hit {
+		atomic.AddUint64(&c.hits, 1)
+		if c.metrics != nil {
+			c.metrics.IncHits()
+		}
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+		if c.metrics != nil {
+			c.metrics.IncMisses()
+		}
+	}
+}
+
+// recordEvictions counts the entries evicted by adds since it was last
+// called, which can be several per add, and returns how many there were.
+// It is called after every add, so it also reports the new size.
+func (c *Cache) recordEvictions() (evicted int) {
+	total := c.lru.Evictions()
+	evicted = int(total - c.lruEvictions)
+	c.lruEvictions = total
+	c.recordEvictionCount(evicted)
+	return evicted
+}
+
+// recordEvictionCount counts n evictions and reports the new size.
+func (c *Cache) recordEvictionCount(n int) {
+	if n > 0 {
+		atomic.AddUint64(&c.evictions, uint64(n))
+		if c.metrics != nil {
+			for i := 0; i < n; i++ {
+				c.metrics.IncEvictions()
+			}
+		}
+	}
+	c.reportSize()
+}
+
+// reportSize tells the metrics collector, if any, the current number of
+// entries. The caller must hold the write lock.
+func (c *Cache) reportSize() {
+	if c.metrics != nil {
+		c.metrics.SetSize(c.lru.Len())
+	}
+}
@@ -1,9 +1,18 @@
 package lru
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/iocn-io/golang-lru/simplelru"
 )
 
 func BenchmarkLRU_Rand(b *testing.B) {
@@ -223,18 +232,42 @@ func TestLRUPeekOrAdd(t *testing.T) {
 	}
 
 	l.Add(3, 3)
-	contains, evict = l.ContainsOrAdd(1, 1)
+	previous, contains, evict = l.PeekOrAdd(1, 1)
 	if contains {
 		t.Errorf("1 should not have been contained")
 	}
 	if !evict {
 		t.Errorf("an eviction should have occurred")
 	}
+	if previous != nil {
+		t.Errorf("previous should be nil")
+	}
 	if !l.Contains(1) {
 		t.Errorf("now 1 should be contained")
 	}
 }
 
+// test that PeekOrAdd treats an expired key as absent
+func TestLRUPeekOrAdd_Expired(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddEx(1, 1, 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	previous, contains, evict := l.PeekOrAdd(1, 10)
+	if contains || previous != nil {
+		t.Errorf("expired 1 should not have been contained: %v", previous)
+	}
+	if evict {
+		t.Errorf("nothing should be evicted here")
+	}
+	if v, ok := l.Peek(1); !ok || v != 10 {
+		t.Errorf("1 should have been overwritten: %v, %v", v, ok)
+	}
+}
+
 // test that Peek doesn't update recent-ness
 func TestLRUPeek(t *testing.T) {
 	l, err := New(2)
@@ -268,13 +301,16 @@ func TestLRUResize(t *testing.T) {
 	// Downsize
 	l.Add(1, 1)
 	l.Add(2, 2)
-	evicted := l.Resize(1)
-	if evicted != 1 {
-		t.Errorf("1 element should have been evicted: %v", evicted)
+	evicted, previous := l.Resize(1)
+	if evicted != 1 || previous != 2 {
+		t.Errorf("1 element should have been evicted: %v, %v", evicted, previous)
 	}
 	if onEvictCounter != 1 {
 		t.Errorf("onEvicted should have been called 1 time: %v", onEvictCounter)
 	}
+	if l.Cap() != 1 {
+		t.Errorf("bad cap: %v", l.Cap())
+	}
 
 	l.Add(3, 3)
 	if l.Contains(1) {
@@ -282,9 +318,12 @@ func TestLRUResize(t *testing.T) {
 	}
 
 	// Upsize
-	evicted = l.Resize(2)
-	if evicted != 0 {
-		t.Errorf("0 elements should have been evicted: %v", evicted)
+	evicted, previous = l.Resize(2)
+	if evicted != 0 || previous != 1 {
+		t.Errorf("0 elements should have been evicted: %v, %v", evicted, previous)
+	}
+	if l.Cap() != 2 {
+		t.Errorf("bad cap: %v", l.Cap())
 	}
 
 	l.Add(4, 4)
@@ -341,3 +380,1485 @@ func TestLRU_Expire(t *testing.T) {
 		t.Fatal("not enough keys")
 	}
 }
+
+// test that LenValid excludes expired entries
+func TestLRULenValid(t *testing.T) {
+	l, err := NewWithExpire(10, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, 60*time.Second)
+	time.Sleep(100 * time.Millisecond)
+	if l.LenValid() != 1 {
+		t.Fatalf("bad valid len: %v", l.LenValid())
+	}
+	if l.Len() != 2 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
+
+// test that the janitor removes expired entries in the background
+func TestLRUJanitor(t *testing.T) {
+	var evictCounter int32
+	onEvicted := func(k interface{}, v interface{}) {
+		atomic.AddInt32(&evictCounter, 1)
+	}
+	l, err := NewWithJanitor(10, 50*time.Millisecond, 10*time.Millisecond, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.AddEx(3, 3, 60*time.Second)
+	time.Sleep(200 * time.Millisecond)
+
+	if l.Len() != 1 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if n := atomic.LoadInt32(&evictCounter); n != 2 {
+		t.Fatalf("bad evict count: %v", n)
+	}
+
+	l.Close()
+	l.Close()
+}
+
+// test that NewWithJanitor rejects a non-positive interval
+func TestLRUJanitor_BadInterval(t *testing.T) {
+	if _, err := NewWithJanitor(10, time.Second, 0, nil); err == nil {
+		t.Fatalf("should have rejected a zero interval")
+	}
+}
+
+// test that GetWithExpire reports the expiry time of an entry
+func TestLRUGetWithExpire(t *testing.T) {
+	l, err := NewWithExpire(10, time.Minute)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	v, expireAt, ok := l.GetWithExpire(1)
+	if !ok || v != 1 {
+		t.Fatalf("1 should be set to 1: %v, %v", v, ok)
+	}
+	if expireAt.IsZero() || expireAt.After(time.Now().Add(time.Minute)) {
+		t.Fatalf("bad expiry for 1: %v", expireAt)
+	}
+}
+
+// test that GetOrAdd returns existing values and updates recent-ness
+func TestLRUGetOrAdd(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	actual, loaded := l.GetOrAdd(1, 10)
+	if !loaded || actual != 1 {
+		t.Errorf("1 should have been loaded: %v, %v", actual, loaded)
+	}
+
+	actual, loaded = l.GetOrAdd(3, 3)
+	if loaded || actual != 3 {
+		t.Errorf("3 should have been added: %v, %v", actual, loaded)
+	}
+	if !l.Contains(1) {
+		t.Errorf("GetOrAdd should have updated recent-ness of 1")
+	}
+	if l.Contains(2) {
+		t.Errorf("2 should have been evicted")
+	}
+}
+
+// test that ContainsOrAdd treats an expired key as absent
+func TestLRUContainsOrAdd_Expired(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddEx(1, 1, 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	contains, evict := l.ContainsOrAdd(1, 10)
+	if contains {
+		t.Errorf("expired 1 should not have been contained")
+	}
+	if evict {
+		t.Errorf("nothing should be evicted here")
+	}
+	if v, ok := l.Get(1); !ok || v != 10 {
+		t.Errorf("1 should have been overwritten: %v, %v", v, ok)
+	}
+}
+
+// test that Stats counts hits, misses and evictions
+func TestLRUStats(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	l.Get(2)
+	l.Get(1)
+	l.Peek(3)
+	l.Peek(4)
+	l.Resize(1)
+
+	stats := l.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Evictions != 2 {
+		t.Fatalf("bad stats: %+v", stats)
+	}
+
+	l.ResetStats()
+	if stats := l.Stats(); stats != (Stats{}) {
+		t.Fatalf("stats should have been reset: %+v", stats)
+	}
+}
+
+// test that Stats counts every eviction of an add that evicts several
+func TestLRUStats_MultiEviction(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// With every entry pinned the cache goes over its size, so the next add
+	// after unpinning evicts two entries at once
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Pin(1)
+	l.Pin(2)
+	if l.Add(3, 3) {
+		t.Fatalf("should not have an eviction")
+	}
+	l.Unpin(1)
+	l.Unpin(2)
+	if !l.Add(4, 4) {
+		t.Fatalf("should have an eviction")
+	}
+	if stats := l.Stats(); stats.Evictions != 2 {
+		t.Fatalf("bad stats: %+v", stats)
+	}
+
+	// Evictions made through Do are not counted
+	l.Do(func(lru *simplelru.LRU) {
+		lru.Add(5, 5)
+	})
+	l.Add(6, 6)
+	if stats := l.Stats(); stats.Evictions != 3 {
+		t.Fatalf("bad stats: %+v", stats)
+	}
+}
+
+// test that the expire callback fires for expired entries removed on Add
+func TestLRUExpireCallback(t *testing.T) {
+	evictCounter, expireCounter := 0, 0
+	onEvicted := func(k interface{}, v interface{}) {
+		evictCounter++
+	}
+	onExpired := func(k interface{}, v interface{}) {
+		expireCounter++
+	}
+	l, err := NewWithExpireCallback(2, 50*time.Millisecond, onEvicted, onExpired)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, time.Minute)
+	time.Sleep(100 * time.Millisecond)
+	l.Add(3, 3)
+	l.Add(4, 4)
+	if expireCounter != 1 || evictCounter != 1 {
+		t.Fatalf("bad counts: %d expired, %d evicted", expireCounter, evictCounter)
+	}
+}
+
+// test that Get refreshes the expiry of a sliding cache
+func TestLRUSlidingExpire(t *testing.T) {
+	l, err := NewWithSlidingExpire(10, 150*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	for i := 0; i < 3; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if _, ok := l.Get(1); !ok {
+			t.Fatalf("1 should have been kept alive by Get")
+		}
+	}
+}
+
+// test that Range visits entries from oldest to newest
+func TestLRURange(t *testing.T) {
+	l, err := New(3)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	var keys []interface{}
+	l.Range(func(k, v interface{}) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if len(keys) != 2 || keys[0] != 1 || keys[1] != 2 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+}
+
+// test that Dump and Load round-trip the cache
+func TestLRUDumpLoad(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add("a", 1)
+	l.Add("b", 2)
+
+	var buf bytes.Buffer
+	if err := l.Dump(&buf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l2, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := l2.Load(&buf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := l2.Keys(); len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Fatalf("bad keys: %v", keys)
+	}
+}
+
+// test that RemoveFunc removes only matching entries
+func TestLRURemoveFunc(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add("tenant1:a", 1)
+	l.Add("tenant2:a", 2)
+	l.Add("tenant1:b", 3)
+	removed := l.RemoveFunc(func(k, v interface{}) bool {
+		return k.(string)[:7] == "tenant1"
+	})
+	if removed != 2 || l.Len() != 1 || !l.Contains("tenant2:a") {
+		t.Fatalf("bad removal: %v, %v", removed, l.Keys())
+	}
+}
+
+// test that GetMany returns hits and misses and updates recent-ness
+func TestLRUGetMany(t *testing.T) {
+	l, err := New(3)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	values, missing := l.GetMany([]interface{}{1, 4, 2, 5})
+	if len(values) != 2 || values[1] != 1 || values[2] != 2 {
+		t.Fatalf("bad values: %v", values)
+	}
+	if len(missing) != 2 || missing[0] != 4 || missing[1] != 5 {
+		t.Fatalf("bad missing: %v", missing)
+	}
+
+	l.Add(6, 6)
+	if l.Contains(3) || !l.Contains(1) || !l.Contains(2) {
+		t.Fatalf("GetMany should have updated recent-ness")
+	}
+	if stats := l.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Fatalf("bad stats: %+v", stats)
+	}
+}
+
+// test that AddMany and AddManyOrdered add every entry
+func TestLRUAddMany(t *testing.T) {
+	l, err := New(3)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	evicted := l.AddMany(map[interface{}]interface{}{1: 1, 2: 2})
+	if evicted != 0 || l.Len() != 2 {
+		t.Fatalf("bad add: %v, %v", evicted, l.Len())
+	}
+
+	evicted = l.AddManyOrdered([]interface{}{3, 4, 5}, []interface{}{3, 4, 5})
+	if evicted != 2 {
+		t.Fatalf("bad evicted count: %v", evicted)
+	}
+	if keys := l.Keys(); len(keys) != 3 || keys[0] != 3 || keys[1] != 4 || keys[2] != 5 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	if stats := l.Stats(); stats.Evictions != 2 {
+		t.Fatalf("bad stats: %+v", stats)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("mismatched lengths should panic")
+		}
+	}()
+	l.AddManyOrdered([]interface{}{1}, nil)
+}
+
+// test that PeekOldest doesn't update recent-ness
+func TestLRUPeekOldest(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if k, _, ok := l.PeekOldest(); !ok || k != 1 {
+		t.Fatalf("bad: %v", k)
+	}
+	l.Add(3, 3)
+	if l.Contains(1) {
+		t.Fatalf("PeekOldest should not have updated recent-ness of 1")
+	}
+}
+
+// test that PeekNewest returns the most recently used entry
+func TestLRUPeekNewest(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Get(1)
+	if k, _, ok := l.PeekNewest(); !ok || k != 1 {
+		t.Fatalf("bad: %v", k)
+	}
+	if k, _, ok := l.GetNewest(); !ok || k != 1 {
+		t.Fatalf("bad: %v", k)
+	}
+}
+
+// test that Values and Entries follow the order of Keys
+func TestLRUValuesEntries(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, "one")
+	l.Add(2, "two")
+	if values := l.Values(); len(values) != 2 || values[0] != "one" || values[1] != "two" {
+		t.Fatalf("bad values: %v", values)
+	}
+	if entries := l.Entries(); len(entries) != 2 || entries[0].Key != 1 || entries[1].Value != "two" {
+		t.Fatalf("bad entries: %v", entries)
+	}
+}
+
+// test that Update replaces values without inserting
+func TestLRUUpdate(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	if !l.Update(1, 10) {
+		t.Fatalf("1 should have been updated")
+	}
+	if l.Update(2, 2) {
+		t.Fatalf("2 should not have been inserted")
+	}
+	if v, _ := l.Peek(1); v != 10 {
+		t.Fatalf("bad value: %v", v)
+	}
+}
+
+// test that Touch updates recent-ness
+func TestLRUTouch(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if !l.Touch(1) {
+		t.Fatalf("1 should have been touched")
+	}
+	l.Add(3, 3)
+	if !l.Contains(1) || l.Contains(2) {
+		t.Fatalf("Touch should have updated recent-ness of 1")
+	}
+}
+
+// test that concurrent GetOrCompute callers share one computation
+func TestLRUGetOrCompute(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var calls int32
+	release := make(chan struct{})
+	compute := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := l.GetOrCompute(context.Background(), "key", compute)
+			if err != nil {
+				t.Errorf("err: %v", err)
+			}
+			results[i] = v
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("compute should have been called once: %v", n)
+	}
+	for _, v := range results {
+		if v != "value" {
+			t.Fatalf("bad result: %v", v)
+		}
+	}
+	if v, ok := l.Peek("key"); !ok || v != "value" {
+		t.Fatalf("result should have been cached: %v, %v", v, ok)
+	}
+
+	// A cached value does not call compute
+	if _, err := l.GetOrCompute(context.Background(), "key", compute); err != nil || calls != 1 {
+		t.Fatalf("compute should not have been called again: %v", err)
+	}
+}
+
+// test that GetOrCompute does not cache errors and honors cancellation
+func TestLRUGetOrCompute_Error(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	failure := errors.New("failed")
+	if _, err := l.GetOrCompute(context.Background(), 1, func(ctx context.Context) (interface{}, error) {
+		return nil, failure
+	}); err != failure {
+		t.Fatalf("bad err: %v", err)
+	}
+	if l.Contains(1) {
+		t.Fatalf("errors should not be cached")
+	}
+
+	release := make(chan struct{})
+	go l.GetOrCompute(context.Background(), 2, func(ctx context.Context) (interface{}, error) {
+		<-release
+		return 2, nil
+	})
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.GetOrCompute(ctx, 2, nil); err != context.Canceled {
+		t.Fatalf("waiter should have been cancelled: %v", err)
+	}
+	close(release)
+
+	// Waiters on a compute that panics get ErrComputePanicked
+	started := make(chan struct{})
+	fail := make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		l.GetOrCompute(context.Background(), 3, func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-fail
+			panic("compute failed")
+		})
+	}()
+	<-started
+	done := make(chan error)
+	go func() {
+		_, err := l.GetOrCompute(context.Background(), 3, nil)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(fail)
+	if err := <-done; !errors.Is(err, ErrComputePanicked) {
+		t.Fatalf("bad err: %v", err)
+	}
+}
+
+// test that negative entries are distinguishable from misses
+func TestLRUNegative(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddNegative(1, time.Minute)
+	if isNegative, ok := l.GetNegative(1); !ok || !isNegative {
+		t.Fatalf("1 should be cached as absent: %v, %v", isNegative, ok)
+	}
+	if _, ok := l.GetNegative(2); ok {
+		t.Fatalf("2 should be a true miss")
+	}
+}
+
+// test that RemoveOldestN removes at most n entries
+func TestLRURemoveOldestN(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+	if removed := l.RemoveOldestN(10); removed != 5 {
+		t.Fatalf("bad removed: %v", removed)
+	}
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
+
+// test that entries read close to expiry are refreshed in the background
+func TestLRURefreshAhead(t *testing.T) {
+	var refreshes int32
+	refresh := func(key interface{}) (interface{}, error) {
+		atomic.AddInt32(&refreshes, 1)
+		return "fresh", nil
+	}
+	l, err := NewWithRefreshAhead(10, 200*time.Millisecond, 100*time.Millisecond, refresh)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, "stale")
+	if v, _ := l.Get(1); v != "stale" {
+		t.Fatalf("bad value: %v", v)
+	}
+	if n := atomic.LoadInt32(&refreshes); n != 0 {
+		t.Fatalf("should not refresh outside the window: %v", n)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if v, _ := l.Get(1); v != "stale" {
+		t.Fatalf("the current value should be served: %v", v)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&refreshes); n != 1 {
+		t.Fatalf("bad refresh count: %v", n)
+	}
+
+	// The refresh replaced the value and restarted its expiry
+	time.Sleep(100 * time.Millisecond)
+	if v, ok := l.Peek(1); !ok || v != "fresh" {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+}
+
+// test that a failed refresh leaves the entry to expire
+func TestLRURefreshAhead_Error(t *testing.T) {
+	refresh := func(key interface{}) (interface{}, error) {
+		return nil, errors.New("unavailable")
+	}
+	if _, err := NewWithRefreshAhead(10, time.Minute, 0, refresh); err == nil {
+		t.Fatalf("should fail on a zero window")
+	}
+	l, err := NewWithRefreshAhead(10, 100*time.Millisecond, 100*time.Millisecond, refresh)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	if v, ok := l.Get(1); !ok || v != 1 {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if _, ok := l.Get(1); ok {
+		t.Fatalf("1 should have expired")
+	}
+}
+
+// test that GetOrLoad loads misses with their TTL and does not cache errors
+func TestLRUGetOrLoad(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	loads := 0
+	loader := func(key interface{}) (interface{}, time.Duration, error) {
+		loads++
+		if key == "bad" {
+			return nil, 0, errors.New("not found")
+		}
+		return key, 50 * time.Millisecond, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if v, err := l.GetOrLoad("a", loader); err != nil || v != "a" {
+			t.Fatalf("bad: %v, %v", v, err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("bad load count: %v", loads)
+	}
+
+	if _, err := l.GetOrLoad("bad", loader); err == nil {
+		t.Fatalf("should return the loader error")
+	}
+	if l.Contains("bad") {
+		t.Fatalf("errors should not be cached")
+	}
+
+	// The loaded TTL applies, so an expired entry is loaded again
+	time.Sleep(100 * time.Millisecond)
+	if v, err := l.GetOrLoad("a", loader); err != nil || v != "a" {
+		t.Fatalf("bad: %v, %v", v, err)
+	}
+	if loads != 3 {
+		t.Fatalf("bad load count: %v", loads)
+	}
+}
+
+func TestLRUGetMultiOrLoad(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+
+	var calls [][]interface{}
+	loader := func(missing []interface{}) (map[interface{}]interface{}, error) {
+		calls = append(calls, append([]interface{}(nil), missing...))
+		loaded := make(map[interface{}]interface{})
+		for _, key := range missing {
+			if key.(int) < 4 {
+				loaded[key] = key
+			}
+		}
+		loaded[99] = 99
+		return loaded, nil
+	}
+
+	values, missing, err := l.GetMultiOrLoad([]interface{}{1, 2, 3, 2, 4}, loader)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(calls) != 1 || len(calls[0]) != 3 || calls[0][0] != 2 || calls[0][1] != 3 || calls[0][2] != 4 {
+		t.Fatalf("bad loader calls: %v", calls)
+	}
+	if len(values) != 3 || values[1] != 1 || values[2] != 2 || values[3] != 3 {
+		t.Fatalf("bad values: %v", values)
+	}
+	if len(missing) != 1 || missing[0] != 4 {
+		t.Fatalf("bad missing: %v", missing)
+	}
+	if !l.Contains(2) || !l.Contains(3) || l.Contains(4) || l.Contains(99) {
+		t.Fatalf("bad contents: %v", l.Keys())
+	}
+
+	// Everything cached needs no load
+	if _, missing, err := l.GetMultiOrLoad([]interface{}{1, 2, 3}, loader); err != nil || missing != nil || len(calls) != 1 {
+		t.Fatalf("bad: %v, %v, %v", missing, err, calls)
+	}
+
+	values, missing, err = l.GetMultiOrLoad([]interface{}{1, 5}, func([]interface{}) (map[interface{}]interface{}, error) {
+		return map[interface{}]interface{}{5: 5}, errors.New("backend down")
+	})
+	if err == nil || len(values) != 1 || len(missing) != 1 || missing[0] != 5 {
+		t.Fatalf("bad: %v, %v, %v", values, missing, err)
+	}
+	if l.Contains(5) {
+		t.Fatalf("errors should not be cached")
+	}
+}
+
+// test that concurrent CompareAndSwap increments are never lost
+func TestLRUCompareAndSwap(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add("n", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for {
+					v, _ := l.Peek("n")
+					if l.CompareAndSwap("n", v, v.(int)+1) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := l.Get("n"); v != 800 {
+		t.Fatalf("bad value: %v", v)
+	}
+	if l.CompareAndSwap("missing", nil, 1) {
+		t.Fatalf("should not swap a missing key")
+	}
+}
+
+// test that concurrent increments are never lost
+func TestLRUIncrement(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add("n", int64(0))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Increment("n", 2)
+				l.Decrement("n", 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := l.Get("n"); v != int64(800) {
+		t.Fatalf("bad value: %v", v)
+	}
+	if _, ok := l.Increment("missing", 1); ok {
+		t.Fatalf("should not increment a missing key")
+	}
+}
+
+// test that Swap returns the previous value and stores the new one
+func TestLRUSwap(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if prev, loaded := l.Swap(1, 1); loaded || prev != nil {
+		t.Fatalf("bad: %v, %v", prev, loaded)
+	}
+	l.Add(2, 2)
+	if prev, loaded := l.Swap(1, 10); !loaded || prev != 1 {
+		t.Fatalf("bad: %v, %v", prev, loaded)
+	}
+	if v, ok := l.Peek(1); !ok || v != 10 {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+
+	// Swap promotes the key, so 2 is evicted next
+	l.Add(3, 3)
+	if l.Contains(2) || !l.Contains(1) {
+		t.Fatalf("2 should have been evicted")
+	}
+}
+
+// test that Map updates and removes entries under the lock
+func TestLRUMap(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+	}
+
+	l.Map(func(k interface{}, v interface{}) (interface{}, bool) {
+		return v.(int) + 1, k != 0
+	})
+	if l.Len() != 3 || l.Contains(0) {
+		t.Fatalf("0 should have been removed")
+	}
+	if v, ok := l.Get(3); !ok || v != 4 {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+}
+
+// test that Do runs several operations atomically
+func TestLRUDo(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Do(func(lru *simplelru.LRU) {
+					n, _ := lru.Peek("n")
+					if n == nil {
+						n = 0
+					}
+					lru.Add("n", n.(int)+1)
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := l.Get("n"); v != 800 {
+		t.Fatalf("bad value: %v", v)
+	}
+}
+
+// test that RemoveExpired sweeps expired entries on demand
+func TestLRURemoveExpired(t *testing.T) {
+	var expired []interface{}
+	onExpired := func(k interface{}, v interface{}) {
+		expired = append(expired, k)
+	}
+	l, err := NewWithExpireCallback(10, time.Minute, nil, onExpired)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	l.SetClock(clock)
+
+	for i := 0; i < 4; i++ {
+		l.AddEx(i, i, time.Duration(i+1)*time.Second)
+	}
+	l.Add(4, 4)
+	clock.Advance(2500 * time.Millisecond)
+
+	if removed := l.RemoveExpired(); removed != 2 {
+		t.Fatalf("bad removed count: %v", removed)
+	}
+	if len(expired) != 2 || expired[0] != 0 || expired[1] != 1 {
+		t.Fatalf("bad expire callbacks: %v", expired)
+	}
+	if l.Len() != 3 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if removed := l.RemoveExpired(); removed != 0 {
+		t.Fatalf("bad removed count: %v", removed)
+	}
+}
+
+// test that a stored nil value is a hit
+func TestLRUNilValue(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, nil)
+	if v, ok := l.Get(1); !ok || v != nil {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if v, ok := l.Peek(1); !ok || v != nil {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if stats := l.Stats(); stats.Hits != 2 || stats.Misses != 0 {
+		t.Fatalf("bad stats: %+v", stats)
+	}
+}
+
+// test that AddIfAbsent only inserts missing or expired keys
+func TestLRUAddIfAbsent(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	l.SetClock(clock)
+
+	if actual, inserted := l.AddIfAbsent(1, 1, time.Second); !inserted || actual != 1 {
+		t.Fatalf("bad: %v, %v", actual, inserted)
+	}
+	if actual, inserted := l.AddIfAbsent(1, 2, time.Second); inserted || actual != 1 {
+		t.Fatalf("bad: %v, %v", actual, inserted)
+	}
+
+	// An expired entry is overwritten with the new TTL
+	clock.Advance(2 * time.Second)
+	if actual, inserted := l.AddIfAbsent(1, 3, time.Minute); !inserted || actual != 3 {
+		t.Fatalf("bad: %v, %v", actual, inserted)
+	}
+	if _, remaining := l.ContainsWithTTL(1); remaining != time.Minute {
+		t.Fatalf("bad remaining: %v", remaining)
+	}
+
+	// Concurrent callers agree on a single winner
+	var wg sync.WaitGroup
+	var winners int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, inserted := l.AddIfAbsent("k", i, 0); inserted {
+				atomic.AddInt32(&winners, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if winners != 1 {
+		t.Fatalf("bad winner count: %v", winners)
+	}
+}
+
+// test that each entry is consumed by exactly one GetDelete
+func TestLRUGetDelete(t *testing.T) {
+	l, err := New(100)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		l.Add(i, i)
+	}
+
+	var wg sync.WaitGroup
+	var consumed int32
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, ok := l.GetDelete(j); ok {
+					atomic.AddInt32(&consumed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if consumed != 100 || l.Len() != 0 {
+		t.Fatalf("bad consumed count or len: %v, %v", consumed, l.Len())
+	}
+}
+
+// test that TryResize rejects non-positive sizes
+func TestLRUErrInvalidSize(t *testing.T) {
+	if _, err := New(0); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := New2Q(0); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewSharded(0, 4, nil); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("err: %v", err)
+	}
+	if !errors.Is(ErrInvalidSize, simplelru.ErrInvalidSize) {
+		t.Fatalf("expected the simplelru error")
+	}
+}
+
+func TestLRUConstructorErrors(t *testing.T) {
+	if _, err := New2QParams(8, 1.5, 0.5); !errors.Is(err, ErrInvalidRatio) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := New2QParams(8, 0.25, -1); !errors.Is(err, ErrInvalidRatio) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewWithPolicy(8, nil); !errors.Is(err, ErrNoPolicy) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewRandom(8, 0); !errors.Is(err, ErrInvalidSampleCount) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewSLRU(0, 4); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("err: %v", err)
+	}
+	refresh := func(interface{}) (interface{}, error) { return nil, nil }
+	if _, err := NewWithRefreshAhead(8, time.Minute, 0, refresh); !errors.Is(err, ErrInvalidRefreshWindow) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewWithRefreshAhead(8, time.Minute, time.Second, nil); !errors.Is(err, ErrNoRefresh) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewWithOptions(WithSize(8), WithJanitor(0)); !errors.Is(err, ErrInvalidJanitorInterval) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewWithOptions(WithSize(8), WithAsyncEvict(0, AsyncEvictBlock)); !errors.Is(err, ErrInvalidEvictBuffer) {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestLRUTryResize(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+
+	for _, size := range []int{0, -5} {
+		if _, _, err := l.TryResize(size); err == nil {
+			t.Fatalf("TryResize(%d) should fail", size)
+		}
+		if l.Cap() != 2 || l.Len() != 2 {
+			t.Fatalf("cache should be unchanged: %v, %v", l.Cap(), l.Len())
+		}
+	}
+	if evicted, _, err := l.TryResize(1); err != nil || evicted != 1 {
+		t.Fatalf("bad: %v, %v", evicted, err)
+	}
+	if l.Stats().Evictions != 1 {
+		t.Fatalf("bad evictions: %v", l.Stats().Evictions)
+	}
+}
+
+// test that All and Backward iterate in opposite orders
+func TestLRUAll(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+
+	n := 0
+	for k, v := range l.All() {
+		if k != n || v != n {
+			t.Fatalf("bad entry: %v, %v", k, v)
+		}
+		n++
+	}
+	for k := range l.Backward() {
+		n--
+		if k != n {
+			t.Fatalf("bad key: %v", k)
+		}
+	}
+	if n != 0 {
+		t.Fatalf("bad count: %v", n)
+	}
+}
+
+// test that concurrent versioned updates are never lost
+func TestLRUUpdateIfVersion(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add("n", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for {
+					v, version, _ := l.GetWithVersion("n")
+					if l.UpdateIfVersion("n", v.(int)+1, version) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := l.Get("n"); v != 800 {
+		t.Fatalf("bad value: %v", v)
+	}
+}
+
+func TestLRUFreeze(t *testing.T) {
+	evictCounter := 0
+	l, err := NewWithEvict(3, func(k interface{}, v interface{}) {
+		evictCounter++
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.AddEx(3, 3, 20*time.Millisecond)
+
+	l.Freeze()
+	if !l.IsFrozen() {
+		t.Fatalf("should be frozen")
+	}
+
+	// Reads work but leave recency alone
+	if v, ok := l.Get(1); !ok || v != 1 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if keys := l.Keys(); keys[0] != 1 {
+		t.Fatalf("Get should not promote while frozen: %v", keys)
+	}
+	if !l.Contains(2) {
+		t.Fatalf("should contain 2")
+	}
+	if values, missing := l.GetMany([]interface{}{1, 4}); len(values) != 1 || len(missing) != 1 {
+		t.Fatalf("bad: %v, %v", values, missing)
+	}
+
+	// Expired entries are hidden but kept
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := l.Peek(3); ok {
+		t.Fatalf("3 should have expired")
+	}
+	if l.Len() != 3 {
+		t.Fatalf("expired entries should not be removed while frozen")
+	}
+
+	// Writes are no-ops
+	if l.Add(4, 4) || l.Contains(4) {
+		t.Fatalf("Add should be a no-op while frozen")
+	}
+	if l.Remove(1) || !l.Contains(1) {
+		t.Fatalf("Remove should be a no-op while frozen")
+	}
+	l.Purge()
+	if evicted, previous := l.Resize(1); evicted != 0 || previous != 3 || l.Len() != 3 {
+		t.Fatalf("Resize should be a no-op while frozen: %v, %v", evicted, previous)
+	}
+	if _, _, err := l.TryResize(1); !errors.Is(err, ErrFrozen) {
+		t.Fatalf("err: %v", err)
+	}
+	if evictCounter != 0 || l.Len() != 3 {
+		t.Fatalf("bad: %v, %v", evictCounter, l.Len())
+	}
+
+	l.Unfreeze()
+	if l.IsFrozen() {
+		t.Fatalf("should not be frozen")
+	}
+	l.Add(4, 4)
+	if _, ok := l.Get(4); !ok {
+		t.Fatalf("Add should work again")
+	}
+	if l.Len() != 3 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
+
+// test that frozen reads can run concurrently, which the race detector
+// checks for mutations
+func TestLRUFreeze_ConcurrentGet(t *testing.T) {
+	l, err := New(128)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 128; i++ {
+		l.Add(i, i)
+	}
+	l.Freeze()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if v, ok := l.Get(i % 128); !ok || v != i%128 {
+					t.Errorf("bad: %v, %v", v, ok)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// test that no method changes a frozen cache
+func TestLRUFreeze_AllMethods(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewWithOptions(WithSize(4), WithSlidingTTL(time.Minute), WithClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.AddEx(1, 1, time.Second)
+	l.Add(2, int64(2))
+	l.AddWithMeta(3, 3, "meta", 0)
+	l.AddNegative(4, 0)
+	clock.Advance(2 * time.Second)
+
+	other, err := New(4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	other.Add(9, 9)
+	var dump bytes.Buffer
+	if err := other.Dump(&dump); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// state describes everything about the entries, including the expired 1
+	state := func() string {
+		var b strings.Builder
+		l.Do(func(lru *simplelru.LRU) {
+			keys, _ := lru.KeysPage(0, lru.Len())
+			fmt.Fprint(&b, lru.Len(), lru.Cap(), lru.PinnedLen(), keys)
+			for _, key := range []interface{}{1, 2, 3, 4, 5, 9} {
+				v, expired, ok := lru.PeekRaw(key)
+				info, _ := lru.Inspect(key)
+				fmt.Fprint(&b, v, expired, ok, info)
+			}
+		})
+		return b.String()
+	}
+	if _, expired, ok := l.PeekRaw(1); !ok || !expired {
+		t.Fatalf("1 should have expired but still be cached")
+	}
+	l.Freeze()
+	before := state()
+
+	_, version, _ := l.GetWithVersion(2)
+	compute := func(context.Context) (interface{}, error) {
+		t.Fatalf("compute should not be called while frozen")
+		return nil, nil
+	}
+	for name, f := range map[string]func(){
+		"Add":                func() { l.Add(5, 5) },
+		"AddEx":              func() { l.AddEx(5, 5, time.Hour) },
+		"AddUntil":           func() { l.AddUntil(5, 5, clock.now.Add(time.Hour)) },
+		"AddMany":            func() { l.AddMany(map[interface{}]interface{}{5: 5}) },
+		"AddManyOrdered":     func() { l.AddManyOrdered([]interface{}{5}, []interface{}{5}) },
+		"AddWithMeta":        func() { l.AddWithMeta(5, 5, "meta", 0) },
+		"AddNegative":        func() { l.AddNegative(5, 0) },
+		"Update":             func() { l.Update(2, int64(20)) },
+		"UpdateIfVersion":    func() { l.UpdateIfVersion(2, int64(20), version) },
+		"CompareAndSwap":     func() { l.CompareAndSwap(2, int64(2), int64(20)) },
+		"CompareAndSwapFunc": func() { l.CompareAndSwapFunc(3, 3, 30, func(a, b interface{}) bool { return a == b }) },
+		"Increment":          func() { l.Increment(2, 1) },
+		"Decrement":          func() { l.Decrement(2, 1) },
+		"Swap":               func() { l.Swap(3, 30) },
+		"GetOrAdd":           func() { l.GetOrAdd(5, 5) },
+		"AddIfAbsent":        func() { l.AddIfAbsent(5, 5, 0) },
+		"ContainsOrAdd":      func() { l.ContainsOrAdd(5, 5) },
+		"PeekOrAdd":          func() { l.PeekOrAdd(5, 5) },
+		"GetDelete":          func() { l.GetDelete(3) },
+		"Remove":             func() { l.Remove(3) },
+		"RemoveFunc":         func() { l.RemoveFunc(func(k, v interface{}) bool { return true }) },
+		"RemovePrefix":       func() { l.RemovePrefix("") },
+		"RemoveExpired":      func() { l.RemoveExpired() },
+		"RemoveOldest":       func() { l.RemoveOldest() },
+		"RemoveOldestN":      func() { l.RemoveOldestN(2) },
+		"Map":                func() { l.Map(func(k, v interface{}) (interface{}, bool) { return nil, false }) },
+		"Drain":              func() { l.Drain() },
+		"Purge":              func() { l.Purge() },
+		"Resize":             func() { l.Resize(1) },
+		"TryResize":          func() { l.TryResize(1) },
+		"Load":               func() { l.Load(bytes.NewReader(dump.Bytes())) },
+		"Pin":                func() { l.Pin(3) },
+		"Unpin":              func() { l.Unpin(3) },
+		"SetTTL":             func() { l.SetTTL(3, time.Hour) },
+		"Touch":              func() { l.Touch(2) },
+		"GetAndRefresh":      func() { l.GetAndRefresh(2, time.Hour) },
+		"GetOrCompute":       func() { l.GetOrCompute(context.Background(), 5, compute) },
+		"GetOrLoad": func() {
+			l.GetOrLoad(5, func(interface{}) (interface{}, time.Duration, error) {
+				t.Fatalf("loader should not be called while frozen")
+				return nil, 0, nil
+			})
+		},
+		"GetMultiOrLoad": func() {
+			l.GetMultiOrLoad([]interface{}{2, 5}, func([]interface{}) (map[interface{}]interface{}, error) {
+				t.Fatalf("loader should not be called while frozen")
+				return nil, nil
+			})
+		},
+		"Get":             func() { l.Get(2) },
+		"GetMany":         func() { l.GetMany([]interface{}{1, 2}) },
+		"GetWithVersion":  func() { l.GetWithVersion(1) },
+		"GetWithExpire":   func() { l.GetWithExpire(2) },
+		"GetNegative":     func() { l.GetNegative(4) },
+		"GetMeta":         func() { l.GetMeta(1) },
+		"Peek":            func() { l.Peek(1) },
+		"PeekWithAccess":  func() { l.PeekWithAccess(1) },
+		"Contains":        func() { l.Contains(1) },
+		"ContainsWithTTL": func() { l.ContainsWithTTL(1) },
+		"Keys":            func() { l.Keys() },
+		"GetOldest":       func() { l.GetOldest() },
+		"GetNewest":       func() { l.GetNewest() },
+	} {
+		f()
+		if after := state(); after != before {
+			t.Fatalf("%s changed the frozen cache:\n%v\n%v", name, before, after)
+		}
+	}
+
+	if _, err := l.GetOrCompute(context.Background(), 5, compute); !errors.Is(err, ErrFrozen) {
+		t.Fatalf("err: %v", err)
+	}
+	if v, err := l.GetOrCompute(context.Background(), 3, compute); err != nil || v != 3 {
+		t.Fatalf("bad: %v, %v", v, err)
+	}
+	if err := l.Load(bytes.NewReader(dump.Bytes())); !errors.Is(err, ErrFrozen) {
+		t.Fatalf("err: %v", err)
+	}
+	if prev, loaded := l.Swap(3, 30); !loaded || prev != 3 {
+		t.Fatalf("bad: %v, %v", prev, loaded)
+	}
+	if actual, loaded := l.GetOrAdd(5, 5); loaded || actual != 5 {
+		t.Fatalf("bad: %v, %v", actual, loaded)
+	}
+}
+
+func TestLRUSetTTL(t *testing.T) {
+	l, err := NewWithExpire(10, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if !l.SetTTL(1, time.Hour) {
+		t.Fatalf("should have set the TTL of 1")
+	}
+	if l.SetTTL(3, time.Hour) {
+		t.Fatalf("should not insert 3")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := l.Get(1); !ok {
+		t.Fatalf("1 should have been extended")
+	}
+	if _, ok := l.Get(2); ok {
+		t.Fatalf("2 should have expired")
+	}
+}
+
+func TestLRUContainsMany(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.AddEx(3, 3, 20*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	found := l.ContainsMany([]interface{}{1, 2, 3, 4})
+	if len(found) != 4 || !found[1] || !found[2] || found[3] || found[4] {
+		t.Fatalf("bad found: %v", found)
+	}
+	if _, expired, ok := l.PeekRaw(3); !ok || !expired {
+		t.Fatalf("expired keys should be left in place")
+	}
+	if keys := l.Keys(); keys[0] != 1 {
+		t.Fatalf("ContainsMany should not update recency: %v", keys)
+	}
+}
+
+func TestLRUPin(t *testing.T) {
+	l, err := New(2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if !l.Pin(1) || l.Pin(3) {
+		t.Fatalf("bad pin results")
+	}
+	if stats := l.Stats(); stats.Pinned != 1 {
+		t.Fatalf("bad stats: %v", stats)
+	}
+	l.Add(3, 3)
+	if !l.Contains(1) || l.Contains(2) {
+		t.Fatalf("bad keys: %v", l.Keys())
+	}
+	if !l.Unpin(1) || l.Stats().Pinned != 0 {
+		t.Fatalf("should have unpinned 1")
+	}
+}
+
+func TestLRUOnReject(t *testing.T) {
+	l, err := New(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	rejected := map[interface{}]interface{}{}
+	l.SetOnReject(func(k interface{}, v interface{}, reason simplelru.RejectReason) {
+		if reason != simplelru.RejectFrozen {
+			t.Fatalf("bad reason: %v", reason)
+		}
+		rejected[k] = v
+	})
+
+	l.Add(1, 1)
+	if len(rejected) != 0 {
+		t.Fatalf("nothing should be rejected: %v", rejected)
+	}
+	l.Freeze()
+	l.Add(2, 2)
+	l.AddEx(3, 3, time.Minute)
+	l.AddManyOrdered([]interface{}{4, 5}, []interface{}{40, 50})
+	if len(rejected) != 4 || rejected[2] != 2 || rejected[3] != 3 || rejected[5] != 50 {
+		t.Fatalf("bad rejected: %v", rejected)
+	}
+}
+
+// test that every add refused while frozen is reported, and lookups that
+// find the key are not
+func TestLRUOnReject_AllAdds(t *testing.T) {
+	l, err := New(4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var rejected []interface{}
+	l.SetOnReject(func(k interface{}, v interface{}, reason simplelru.RejectReason) {
+		rejected = append(rejected, k, v)
+	})
+	l.Add(1, 1)
+	l.Freeze()
+
+	for _, tc := range []struct {
+		name string
+		add  func()
+		want []interface{}
+	}{
+		{"AddWithMeta", func() { l.AddWithMeta(2, 2, "meta", 0) }, []interface{}{2, 2}},
+		{"AddNegative", func() { l.AddNegative(2, 0) }, []interface{}{2, nil}},
+		{"Swap", func() { l.Swap(1, 10) }, []interface{}{1, 10}},
+		{"GetOrAdd", func() { l.GetOrAdd(2, 2) }, []interface{}{2, 2}},
+		{"GetOrAdd hit", func() { l.GetOrAdd(1, 10) }, nil},
+		{"ContainsOrAdd", func() { l.ContainsOrAdd(2, 2) }, []interface{}{2, 2}},
+		{"ContainsOrAdd hit", func() { l.ContainsOrAdd(1, 10) }, nil},
+		{"PeekOrAdd", func() { l.PeekOrAdd(2, 2) }, []interface{}{2, 2}},
+		{"PeekOrAdd hit", func() { l.PeekOrAdd(1, 10) }, nil},
+		{"AddIfAbsent", func() { l.AddIfAbsent(2, 2, 0) }, []interface{}{2, 2}},
+		{"AddIfAbsent hit", func() { l.AddIfAbsent(1, 10, 0) }, nil},
+	} {
+		rejected = nil
+		tc.add()
+		if fmt.Sprint(rejected) != fmt.Sprint(tc.want) {
+			t.Fatalf("%s: bad rejected: %v", tc.name, rejected)
+		}
+	}
+	if l.Len() != 1 {
+		t.Fatalf("nothing should have been added: %v", l.Keys())
+	}
+}
@@ -0,0 +1,35 @@
+package lru
+
+// Metrics receives the events a Cache counts, so they can be forwarded to a
+// monitoring system such as expvar or Prometheus without this package
+// depending on it. Hits and misses may be reported concurrently, so
+// implementations must be safe for concurrent use. The expvarmetrics
+// package provides an expvar-backed implementation.
+type Metrics interface {
+	// IncHits counts a lookup that found its key.
+	IncHits()
+	// IncMisses counts a lookup that did not find its key.
+	IncMisses()
+	// IncEvictions counts an entry evicted to make room or by Resize.
+	IncEvictions()
+	// SetSize reports the number of entries after an operation that
+	// added or removed them. Entries dropped lazily when found expired
+	// are only reflected at the next such operation.
+	SetSize(size int)
+}
+
+// NoopMetrics is a Metrics that discards every event. It can be embedded
+// by implementations that only care about some of them.
+type NoopMetrics struct{}
+
+// IncHits does nothing.
+func (NoopMetrics) IncHits() {}
+
+// IncMisses does nothing.
+func (NoopMetrics) IncMisses() {}
+
+// IncEvictions does nothing.
+func (NoopMetrics) IncEvictions() {}
+
+// SetSize does nothing.
+func (NoopMetrics) SetSize(size int) {}
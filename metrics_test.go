@@ -0,0 +1,59 @@
+package lru
+
+import "testing"
+
+// countingMetrics records the events reported to it
+type countingMetrics struct {
+	NoopMetrics
+	hits, misses, evictions, size int
+}
+
+func (m *countingMetrics) IncHits()         { m.hits++ }
+func (m *countingMetrics) IncMisses()       { m.misses++ }
+func (m *countingMetrics) IncEvictions()    { m.evictions++ }
+func (m *countingMetrics) SetSize(size int) { m.size = size }
+
+func TestLRUMetrics(t *testing.T) {
+	l, err := New(4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(0, 0)
+	m := &countingMetrics{}
+	l.SetMetrics(m)
+	if m.size != 1 {
+		t.Fatalf("registering should report the size: %v", m.size)
+	}
+
+	for i := 1; i < 6; i++ {
+		l.Add(i, i)
+	}
+	l.Get(5)
+	l.Get(0)
+	l.Peek(4)
+	if m.hits != 2 || m.misses != 1 || m.evictions != 2 || m.size != 4 {
+		t.Fatalf("bad metrics: %+v", m)
+	}
+
+	l.Resize(2)
+	if m.evictions != 4 || m.size != 2 {
+		t.Fatalf("bad metrics: %+v", m)
+	}
+	l.Remove(5)
+	if m.size != 1 {
+		t.Fatalf("bad size: %v", m.size)
+	}
+	l.Purge()
+	if m.size != 0 {
+		t.Fatalf("bad size: %v", m.size)
+	}
+	if stats := l.Stats(); stats.Hits != 2 || stats.Evictions != 4 {
+		t.Fatalf("Stats should still be counted: %+v", stats)
+	}
+
+	l.SetMetrics(nil)
+	l.Get(1)
+	if m.misses != 1 {
+		t.Fatalf("metrics should be disabled")
+	}
+}
@@ -0,0 +1,262 @@
+package lru
+
+import (
+	"time"
+
+	"github.com/iocn-io/golang-lru/simplelru"
+)
+
+// Option configures a Cache constructed by NewWithOptions.
+type Option func(*options)
+
+// options holds the settings collected from the Options
+type options struct {
+	size        int
+	ttl         time.Duration
+	sliding     bool
+	trackAccess bool
+	maxAge      time.Duration
+	growMax     int
+	growFactor  float64
+	minTTL      time.Duration
+	onTTLClamp  simplelru.TTLClampCallback
+	onEvicted   func(key interface{}, value interface{})
+	onExpired   func(key interface{}, value interface{})
+	onReason    simplelru.EvictCallbackWithReason
+	onMeta      simplelru.EvictCallbackWithMeta
+	clock       simplelru.Clock
+	metrics     Metrics
+	clone       CloneFunc
+	cloneAdd    bool
+	janitor     time.Duration
+	evictBuffer int
+	evictPolicy AsyncEvictPolicy
+	// asyncEvict distinguishes WithAsyncEvict(0, ...), which is rejected,
+	// from synchronous callbacks
+	asyncEvict bool
+	// hasJanitor distinguishes WithJanitor(0), which is rejected, from no
+	// janitor at all
+	hasJanitor bool
+}
+
+// WithSize sets the maximum number of entries. It is required.
+func WithSize(size int) Option {
+	return func(o *options) {
+		o.size = size
+	}
+}
+
+// WithTTL makes entries expire the given duration after they are added.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+		o.sliding = false
+	}
+}
+
+// WithSlidingTTL makes entries expire the given duration after they were
+// last read, as in NewWithSlidingExpire.
+func WithSlidingTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+		o.sliding = true
+	}
+}
+
+// WithAccessTracking makes reads record when each entry was last accessed,
+// as reported by PeekWithAccess, as in simplelru's SetTrackAccess. It costs
+// a clock read on every hit.
+func WithAccessTracking() Option {
+	return func(o *options) {
+		o.trackAccess = true
+	}
+}
+
+// WithMaxAge caps how long any entry can live, counted from when it was
+// added, regardless of the TTL it was given, as in simplelru's SetMaxAge.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(o *options) {
+		o.maxAge = maxAge
+	}
+}
+
+// WithAutoGrow lets a full cache grow its size by growFactor instead of
+// evicting, up to maxSize entries, as in simplelru's SetAutoGrow. WithSize
+// sets the starting size, and Cap reports the current one.
+func WithAutoGrow(maxSize int, growFactor float64) Option {
+	return func(o *options) {
+		o.growMax = maxSize
+		o.growFactor = growFactor
+	}
+}
+
+// WithMinTTL raises any positive per-key TTL below minTTL to minTTL, as in
+// simplelru's SetMinTTL, calling onClamp, if not nil, for each TTL raised.
+// onClamp is called with the cache locked and must not use it.
+func WithMinTTL(minTTL time.Duration, onClamp simplelru.TTLClampCallback) Option {
+	return func(o *options) {
+		o.minTTL = minTTL
+		o.onTTLClamp = onClamp
+	}
+}
+
+// WithEvict sets the callback invoked when an entry is removed.
+func WithEvict(onEvicted func(key interface{}, value interface{})) Option {
+	return func(o *options) {
+		o.onEvicted = onEvicted
+	}
+}
+
+// WithExpireCallback sets a callback invoked instead of the one set with
+// WithEvict when an entry is removed because it expired, as in
+// NewWithExpireCallback. It is always called synchronously, even with
+// WithAsyncEvict.
+func WithExpireCallback(onExpired func(key interface{}, value interface{})) Option {
+	return func(o *options) {
+		o.onExpired = onExpired
+	}
+}
+
+// WithEvictReason sets a callback invoked for every removal along with its
+// reason, as in NewWithReason. It is always called synchronously.
+func WithEvictReason(onEvicted simplelru.EvictCallbackWithReason) Option {
+	return func(o *options) {
+		o.onReason = onEvicted
+	}
+}
+
+// WithEvictMeta sets a callback invoked for every removal along with the
+// entry's metadata, as in NewWithMetaCallback. It is always called
+// synchronously.
+func WithEvictMeta(onEvicted simplelru.EvictCallbackWithMeta) Option {
+	return func(o *options) {
+		o.onMeta = onEvicted
+	}
+}
+
+// WithClock sets the time source used for expiry.
+func WithClock(clock simplelru.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithMetrics registers a collector for the cache's events, as in
+// SetMetrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
+
+// WithCloneFunc makes every method that hands out a cached value, from Get
+// and Peek to Range, Values, Snapshot and the GetOrAdd family, return
+// clone(value) instead of the cached value itself, so callers can modify
+// what they get without corrupting the cache. Every read then pays for a
+// copy, so this is off by default. nil values are never cloned, and
+// eviction callbacks receive the cached values.
+func WithCloneFunc(clone CloneFunc) Option {
+	return func(o *options) {
+		o.clone = clone
+	}
+}
+
+// WithCloneOnAdd makes every method that stores a value, from Add and AddEx
+// to AddMany, Swap, Update and the GetOrAdd family, store a copy made with
+// the function given to WithCloneFunc, so callers can also keep modifying
+// values after adding them.
+func WithCloneOnAdd() Option {
+	return func(o *options) {
+		o.cloneAdd = true
+	}
+}
+
+// WithJanitor starts a background goroutine that removes expired entries
+// every interval, as in NewWithJanitor. Close must be called to stop it.
+func WithJanitor(interval time.Duration) Option {
+	return func(o *options) {
+		o.janitor = interval
+		o.hasJanitor = true
+	}
+}
+
+// WithAsyncEvict makes the callback set with WithEvict run on a separate
+// goroutine instead of inside the cache's critical section, so a slow
+// callback does not block other operations. Evictions are queued in a
+// buffer of the given size, and policy decides what happens when it is
+// full. The callback is still called for one eviction at a time and in the
+// order they occurred, but possibly after the operation that caused them
+// has returned. Close must be called to report the queued evictions and
+// stop the goroutine.
+func WithAsyncEvict(buffer int, policy AsyncEvictPolicy) Option {
+	return func(o *options) {
+		o.evictBuffer = buffer
+		o.evictPolicy = policy
+		o.asyncEvict = true
+	}
+}
+
+// NewWithOptions constructs a cache configured by the given options.
+// WithSize must be among them; later options override earlier ones.
+func NewWithOptions(opts ...Option) (*Cache, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.hasJanitor && o.janitor <= 0 {
+		return nil, ErrInvalidJanitorInterval
+	}
+	if o.asyncEvict && o.evictBuffer <= 0 {
+		return nil, ErrInvalidEvictBuffer
+	}
+
+	c := &Cache{}
+	onEvicted := o.onEvicted
+	if o.asyncEvict && onEvicted != nil {
+		c.onEvicted = onEvicted
+		c.evictPolicy = o.evictPolicy
+		onEvicted = c.enqueueEvicted
+	}
+
+	var lru *simplelru.LRU
+	var err error
+	if o.sliding {
+		lru, err = simplelru.NewLRUWithSlidingExpire(o.size, o.ttl, onEvicted)
+	} else {
+		lru, err = simplelru.NewLRUWithExpire(o.size, o.ttl, onEvicted)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.lru = lru
+	c.lru.SetOnExpire(o.onExpired)
+	c.lru.SetOnEvictReason(o.onReason)
+	c.lru.SetOnEvictMeta(o.onMeta)
+	c.lru.SetTrackAccess(o.trackAccess)
+	c.lru.SetMaxAge(o.maxAge)
+	c.lru.SetMinTTL(o.minTTL)
+	c.lru.SetOnTTLClamp(o.onTTLClamp)
+	if err := c.lru.SetAutoGrow(o.growMax, o.growFactor); err != nil {
+		return nil, err
+	}
+	if c.onEvicted != nil {
+		c.evictQueue = make(chan evictedEntry, o.evictBuffer)
+		c.evictDone = make(chan struct{})
+		go c.runEvictions()
+	}
+	if o.clock != nil {
+		c.clock = o.clock
+		c.lru.SetClock(o.clock)
+	}
+	if o.metrics != nil {
+		c.SetMetrics(o.metrics)
+	}
+	c.clone = o.clone
+	c.cloneOnAdd = o.cloneAdd
+	if o.hasJanitor {
+		c.stopJanitor = make(chan struct{})
+		c.janitorDone = make(chan struct{})
+		go c.runJanitor(o.janitor)
+	}
+	return c, nil
+}
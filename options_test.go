@@ -0,0 +1,353 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iocn-io/golang-lru/simplelru"
+)
+
+// fakeClock is a Clock that only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestNewWithOptions(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		evictCounter++
+	}
+	clock := &fakeClock{now: time.Now()}
+	l, err := NewWithOptions(WithSize(2), WithTTL(time.Minute), WithEvict(onEvicted), WithClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	if evictCounter != 1 || l.Cap() != 2 {
+		t.Fatalf("bad evict count or cap: %v, %v", evictCounter, l.Cap())
+	}
+	clock.Advance(2 * time.Minute)
+	if _, ok := l.Get(3); ok {
+		t.Fatalf("3 should have expired")
+	}
+}
+
+// test that the expire, reason and meta callbacks compose with other options
+func TestNewWithOptions_Callbacks(t *testing.T) {
+	var evicted, expired []interface{}
+	var reasons []simplelru.EvictReason
+	var metas []interface{}
+	clock := &fakeClock{now: time.Now()}
+	l, err := NewWithOptions(WithSize(2), WithTTL(time.Minute), WithClock(clock),
+		WithEvict(func(k, v interface{}) { evicted = append(evicted, k) }),
+		WithExpireCallback(func(k, v interface{}) { expired = append(expired, k) }),
+		WithEvictReason(func(k, v interface{}, reason simplelru.EvictReason) { reasons = append(reasons, reason) }),
+		WithEvictMeta(func(k, v, meta interface{}) { metas = append(metas, meta) }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddWithMeta(1, 1, "one", 0)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	clock.Advance(2 * time.Minute)
+	if _, ok := l.Get(2); ok {
+		t.Fatalf("2 should have expired")
+	}
+	if len(evicted) != 1 || evicted[0] != 1 || len(expired) != 1 || expired[0] != 2 {
+		t.Fatalf("bad callbacks: %v, %v", evicted, expired)
+	}
+	if len(reasons) != 1 || reasons[0] != simplelru.ReasonCapacity || len(metas) != 1 || metas[0] != "one" {
+		t.Fatalf("bad reasons or metas: %v, %v", reasons, metas)
+	}
+
+	// The legacy constructors validate like NewWithOptions
+	if _, err := NewWithExpireCallback(0, 0, nil, nil); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("bad err: %v", err)
+	}
+	if _, err := NewWithReason(0, 0, nil); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("bad err: %v", err)
+	}
+	if _, err := NewWithMetaCallback(0, 0, nil); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("bad err: %v", err)
+	}
+}
+
+// test that reads only update access times with WithAccessTracking
+func TestNewWithOptions_AccessTracking(t *testing.T) {
+	for _, track := range []bool{false, true} {
+		clock := &fakeClock{now: time.Now()}
+		opts := []Option{WithSize(2), WithClock(clock)}
+		if track {
+			opts = append(opts, WithAccessTracking())
+		}
+		l, err := NewWithOptions(opts...)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		added := clock.Now()
+		l.Add(1, 1)
+		clock.Advance(time.Second)
+		l.Get(1)
+		want := added
+		if track {
+			want = clock.Now()
+		}
+		if _, lastAccess, ok := l.PeekWithAccess(1); !ok || !lastAccess.Equal(want) {
+			t.Fatalf("bad access time with tracking %v: %v", track, lastAccess)
+		}
+	}
+}
+
+func TestNewWithOptions_SlidingTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	l, err := NewWithOptions(WithSize(2), WithSlidingTTL(time.Minute), WithClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	for i := 0; i < 3; i++ {
+		clock.Advance(45 * time.Second)
+		if _, ok := l.Get(1); !ok {
+			t.Fatalf("reads should keep 1 alive")
+		}
+	}
+	clock.Advance(2 * time.Minute)
+	if _, ok := l.Get(1); ok {
+		t.Fatalf("1 should have expired")
+	}
+}
+
+func TestNewWithOptions_Janitor(t *testing.T) {
+	if _, err := NewWithOptions(WithSize(2), WithJanitor(0)); err == nil {
+		t.Fatalf("should fail on a zero janitor interval")
+	}
+	l, err := NewWithOptions(WithSize(2), WithTTL(50*time.Millisecond), WithJanitor(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+
+	l.Add(1, 1)
+	time.Sleep(100 * time.Millisecond)
+	if l.Len() != 0 {
+		t.Fatalf("janitor should have removed 1")
+	}
+}
+
+func TestNewWithOptions_Invalid(t *testing.T) {
+	if _, err := NewWithOptions(); err == nil {
+		t.Fatalf("should fail without a size")
+	}
+	if _, err := NewWithOptions(WithSize(-1)); err == nil {
+		t.Fatalf("should fail on a negative size")
+	}
+}
+
+func TestNewWithOptions_CloneFunc(t *testing.T) {
+	clones := 0
+	clone := func(v interface{}) interface{} {
+		clones++
+		return append([]int(nil), v.([]int)...)
+	}
+	l, err := NewWithOptions(WithSize(2), WithCloneFunc(clone))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, []int{1, 2})
+	v, _ := l.Get(1)
+	v.([]int)[0] = 10
+	if v, _ := l.Peek(1); v.([]int)[0] != 1 {
+		t.Fatalf("modifying a read value should not change the cache: %v", v)
+	}
+	if clones != 2 {
+		t.Fatalf("bad clone count: %v", clones)
+	}
+
+	// nil values are returned as they are
+	l.Add(2, nil)
+	if v, ok := l.Get(2); !ok || v != nil || clones != 2 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+}
+
+func TestNewWithOptions_CloneOnAdd(t *testing.T) {
+	clone := func(v interface{}) interface{} {
+		return append([]int(nil), v.([]int)...)
+	}
+	l, err := NewWithOptions(WithSize(2), WithCloneFunc(clone), WithCloneOnAdd())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	value := []int{1, 2}
+	l.Add(1, value)
+	value[0] = 10
+	if v, _ := l.Get(1); v.([]int)[0] != 1 {
+		t.Fatalf("modifying an added value should not change the cache: %v", v)
+	}
+}
+
+// test that every read path hands out copies and every add path stores them
+func TestNewWithOptions_CloneAllPaths(t *testing.T) {
+	clone := func(v interface{}) interface{} {
+		return append([]int(nil), v.([]int)...)
+	}
+	l, err := NewWithOptions(WithSize(8), WithCloneFunc(clone))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	mutate := func(v interface{}) {
+		v.([]int)[0] = 100
+	}
+	check := func(name string, key interface{}) {
+		t.Helper()
+		if v, ok := l.Peek(key); !ok || v.([]int)[0] != 1 {
+			t.Fatalf("%s should not return the cached value: %v", name, v)
+		}
+	}
+
+	l.Add(1, []int{1})
+	l.Range(func(k, v interface{}) bool {
+		mutate(v)
+		return true
+	})
+	check("Range", 1)
+	mutate(l.Values()[0])
+	check("Values", 1)
+	mutate(l.Entries()[0].Value)
+	check("Entries", 1)
+	mutate(l.Snapshot()[0].Value)
+	check("Snapshot", 1)
+	for name, get := range map[string]func() (interface{}, interface{}, bool){
+		"GetOldest":  l.GetOldest,
+		"PeekOldest": l.PeekOldest,
+		"GetNewest":  l.GetNewest,
+		"PeekNewest": l.PeekNewest,
+	} {
+		_, v, _ := get()
+		mutate(v)
+		check(name, 1)
+	}
+	v, _ := l.GetOrAdd(1, []int{1})
+	mutate(v)
+	check("GetOrAdd", 1)
+	v, _ = l.AddIfAbsent(1, []int{1}, 0)
+	mutate(v)
+	check("AddIfAbsent", 1)
+	v, _, _ = l.PeekOrAdd(1, []int{1})
+	mutate(v)
+	check("PeekOrAdd", 1)
+	v, _, _ = l.PeekRaw(1)
+	mutate(v)
+	check("PeekRaw", 1)
+	values, _ := l.GetMany([]interface{}{1})
+	mutate(values[1])
+	check("GetMany", 1)
+	v, _ = l.GetOrCompute(context.Background(), 1, nil)
+	mutate(v)
+	check("GetOrCompute", 1)
+
+	// Values produced by loaders are copied on the way out too
+	values, _, _ = l.GetMultiOrLoad([]interface{}{1, 2}, func(missing []interface{}) (map[interface{}]interface{}, error) {
+		return map[interface{}]interface{}{2: []int{1}}, nil
+	})
+	mutate(values[1])
+	mutate(values[2])
+	check("GetMultiOrLoad", 1)
+	check("GetMultiOrLoad", 2)
+	v, _ = l.GetOrLoad(3, func(interface{}) (interface{}, time.Duration, error) {
+		return []int{1}, 0, nil
+	})
+	mutate(v)
+	check("GetOrLoad", 3)
+
+	// With WithCloneOnAdd every add stores a copy
+	l, err = NewWithOptions(WithSize(8), WithCloneFunc(clone), WithCloneOnAdd())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	value := []int{1}
+	l.AddMany(map[interface{}]interface{}{1: value})
+	l.AddManyOrdered([]interface{}{2}, []interface{}{value})
+	l.AddUntil(3, value, time.Time{})
+	l.Swap(4, value)
+	l.GetOrAdd(5, value)
+	l.ContainsOrAdd(6, value)
+	l.PeekOrAdd(7, value)
+	l.AddIfAbsent(8, value, 0)
+	mutate(value)
+	for key := 1; key <= 8; key++ {
+		check("add", key)
+	}
+	value = []int{1}
+	l.Update(1, value)
+	mutate(value)
+	check("Update", 1)
+}
+
+func TestNewWithOptions_MaxAge(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewWithOptions(WithSize(2), WithClock(clock), WithMaxAge(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddEx(1, 1, time.Hour)
+	clock.Advance(2 * time.Minute)
+	if _, ok := l.Get(1); ok {
+		t.Fatalf("1 should have expired by the max age")
+	}
+}
+
+func TestNewWithOptions_AutoGrow(t *testing.T) {
+	if _, err := NewWithOptions(WithSize(4), WithAutoGrow(8, 0.5)); !errors.Is(err, simplelru.ErrInvalidGrowFactor) {
+		t.Fatalf("should fail on a grow factor below 1: %v", err)
+	}
+	l, err := NewWithOptions(WithSize(2), WithAutoGrow(4, 2))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+	if l.Cap() != 4 || l.Len() != 4 || l.Contains(0) {
+		t.Fatalf("bad cap or len: %v, %v", l.Cap(), l.Len())
+	}
+}
+
+func TestNewWithOptions_MinTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	clamps := 0
+	l, err := NewWithOptions(WithSize(2), WithClock(clock), WithMinTTL(time.Second, func(k interface{}, requested, clamped time.Duration) {
+		clamps++
+	}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddEx(1, 1, time.Nanosecond)
+	l.AddEx(2, 2, time.Minute)
+	clock.Advance(500 * time.Millisecond)
+	if _, ok := l.Get(1); !ok {
+		t.Fatalf("1 should not have expired")
+	}
+	if clamps != 1 {
+		t.Fatalf("bad clamp count: %v", clamps)
+	}
+}
@@ -0,0 +1,119 @@
+package lru
+
+import (
+	"sync"
+
+	"github.com/iocn-io/golang-lru/simplelru"
+)
+
+// PolicyCache is a thread-safe fixed size cache that delegates the choice
+// of which entry to evict to a pluggable simplelru.EvictionPolicy. The
+// simplelru package provides LRU, LFU and FIFO policies; custom policies
+// can implement domain-specific eviction.
+type PolicyCache struct {
+	size   int
+	items  map[interface{}]interface{}
+	policy simplelru.EvictionPolicy
+	lock   sync.RWMutex
+}
+
+// NewWithPolicy creates a PolicyCache of the given size that evicts the
+// keys chosen by policy. The policy must be empty and must not be shared
+// with another cache.
+func NewWithPolicy(size int, policy simplelru.EvictionPolicy) (*PolicyCache, error) {
+	if size <= 0 {
+		return nil, ErrInvalidSize
+	}
+	if policy == nil {
+		return nil, ErrNoPolicy
+	}
+	c := &PolicyCache{
+		size:   size,
+		items:  make(map[interface{}]interface{}, size),
+		policy: policy,
+	}
+	return c, nil
+}
+
+// Get looks up a key's value from the cache, recording the access with the
+// policy.
+func (c *PolicyCache) Get(key interface{}) (value interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if value, ok = c.items[key]; ok {
+		c.policy.Record(key)
+	}
+	return value, ok
+}
+
+// Add adds a value to the cache. Returns true if an eviction occurred.
+func (c *PolicyCache) Add(key, value interface{}) (evicted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.items[key]; !ok && len(c.items) >= c.size {
+		delete(c.items, c.policy.Evict())
+		evicted = true
+	}
+	c.items[key] = value
+	c.policy.Record(key)
+	return evicted
+}
+
+// Contains checks if a key is in the cache, without recording an access.
+func (c *PolicyCache) Contains(key interface{}) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	_, ok := c.items[key]
+	return ok
+}
+
+// Peek returns the key value (or undefined if not found) without recording
+// an access.
+func (c *PolicyCache) Peek(key interface{}) (value interface{}, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	value, ok = c.items[key]
+	return value, ok
+}
+
+// Remove removes the provided key from the cache, returning if the
+// key was contained.
+func (c *PolicyCache) Remove(key interface{}) (present bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.items[key]; ok {
+		delete(c.items, key)
+		c.policy.Remove(key)
+		return true
+	}
+	return false
+}
+
+// Keys returns a slice of the keys in the cache, in no particular order.
+func (c *PolicyCache) Keys() []interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	keys := make([]interface{}, 0, len(c.items))
+	for key := range c.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Len returns the number of items in the cache.
+func (c *PolicyCache) Len() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return len(c.items)
+}
+
+// Purge is used to completely clear the cache.
+func (c *PolicyCache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key := range c.items {
+		c.policy.Remove(key)
+		delete(c.items, key)
+	}
+}
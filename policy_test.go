@@ -0,0 +1,94 @@
+package lru
+
+import (
+	"testing"
+
+	"github.com/iocn-io/golang-lru/simplelru"
+)
+
+func TestPolicyCache(t *testing.T) {
+	l, err := NewWithPolicy(2, simplelru.NewLRUPolicy())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Get(1)
+	if !l.Add(3, 3) {
+		t.Fatalf("should have an eviction")
+	}
+	if l.Contains(2) || !l.Contains(1) || !l.Contains(3) {
+		t.Fatalf("2 should have been evicted: %v", l.Keys())
+	}
+	if l.Add(3, 30) {
+		t.Fatalf("updating should not evict")
+	}
+	if v, ok := l.Peek(3); !ok || v != 30 {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+
+	if !l.Remove(1) || l.Remove(1) {
+		t.Fatalf("bad remove")
+	}
+	l.Add(4, 4)
+	l.Add(5, 5)
+	if l.Contains(3) || l.Len() != 2 {
+		t.Fatalf("3 should have been evicted: %v", l.Keys())
+	}
+	l.Purge()
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
+
+// lastInPolicy is a custom policy that evicts the most recently added key
+type lastInPolicy struct {
+	keys []interface{}
+}
+
+func (p *lastInPolicy) Record(key interface{}) {
+	for _, k := range p.keys {
+		if k == key {
+			return
+		}
+	}
+	p.keys = append(p.keys, key)
+}
+
+func (p *lastInPolicy) Evict() interface{} {
+	key := p.keys[len(p.keys)-1]
+	p.keys = p.keys[:len(p.keys)-1]
+	return key
+}
+
+func (p *lastInPolicy) Remove(key interface{}) {
+	for i, k := range p.keys {
+		if k == key {
+			p.keys = append(p.keys[:i], p.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+func TestPolicyCache_Custom(t *testing.T) {
+	l, err := NewWithPolicy(2, &lastInPolicy{})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	if !l.Contains(1) || l.Contains(2) || !l.Contains(3) {
+		t.Fatalf("2 should have been evicted: %v", l.Keys())
+	}
+}
+
+func TestNewWithPolicy_Invalid(t *testing.T) {
+	if _, err := NewWithPolicy(0, simplelru.NewLRUPolicy()); err == nil {
+		t.Fatalf("should fail on a zero size")
+	}
+	if _, err := NewWithPolicy(2, nil); err == nil {
+		t.Fatalf("should fail without a policy")
+	}
+}
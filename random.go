@@ -0,0 +1,171 @@
+package lru
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RandomCache is a thread-safe fixed size cache that evicts by random
+// sampling instead of maintaining a recency list. On overflow it samples
+// sampleK entries at random and evicts the least recently used of them, so
+// a sampleK of 1 evicts a uniformly random entry while larger values
+// approximate LRU more closely. Inserts and lookups are O(1) in the worst
+// case regardless of the cache size.
+type RandomCache struct {
+	size    int
+	sampleK int
+
+	items   map[interface{}]int // key to index in entries
+	entries []*randomEntry
+	clock   uint64 // logical time of the last access
+	rand    *rand.Rand
+	lock    sync.RWMutex
+}
+
+// randomEntry is used to hold a value in the entries slice
+type randomEntry struct {
+	key      interface{}
+	value    interface{}
+	lastUsed uint64
+}
+
+// NewRandom creates a RandomCache of the given size that samples sampleK
+// entries when choosing which one to evict.
+func NewRandom(size, sampleK int) (*RandomCache, error) {
+	if size <= 0 {
+		return nil, ErrInvalidSize
+	}
+	if sampleK <= 0 {
+		return nil, ErrInvalidSampleCount
+	}
+	c := &RandomCache{
+		size:    size,
+		sampleK: sampleK,
+		items:   make(map[interface{}]int, size),
+		entries: make([]*randomEntry, 0, size),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	return c, nil
+}
+
+// Get looks up a key's value from the cache.
+func (c *RandomCache) Get(key interface{}) (value interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if i, ok := c.items[key]; ok {
+		ent := c.entries[i]
+		c.clock++
+		ent.lastUsed = c.clock
+		return ent.value, true
+	}
+	return nil, false
+}
+
+// Add adds a value to the cache. Returns true if an eviction occurred.
+func (c *RandomCache) Add(key, value interface{}) (evicted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.clock++
+	if i, ok := c.items[key]; ok {
+		ent := c.entries[i]
+		ent.value = value
+		ent.lastUsed = c.clock
+		return false
+	}
+
+	if len(c.entries) >= c.size {
+		c.removeIndex(c.victim())
+		evicted = true
+	}
+	c.items[key] = len(c.entries)
+	c.entries = append(c.entries, &randomEntry{key: key, value: value, lastUsed: c.clock})
+	return evicted
+}
+
+// Contains checks if a key is in the cache, without updating the
+// recent-ness.
+func (c *RandomCache) Contains(key interface{}) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	_, ok := c.items[key]
+	return ok
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the "recently used"-ness of the key.
+func (c *RandomCache) Peek(key interface{}) (value interface{}, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if i, ok := c.items[key]; ok {
+		return c.entries[i].value, true
+	}
+	return nil, false
+}
+
+// Remove removes the provided key from the cache, returning if the
+// key was contained.
+func (c *RandomCache) Remove(key interface{}) (present bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if i, ok := c.items[key]; ok {
+		c.removeIndex(i)
+		return true
+	}
+	return false
+}
+
+// Keys returns a slice of the keys in the cache, in no particular order.
+func (c *RandomCache) Keys() []interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	keys := make([]interface{}, len(c.entries))
+	for i, ent := range c.entries {
+		keys[i] = ent.key
+	}
+	return keys
+}
+
+// Len returns the number of items in the cache.
+func (c *RandomCache) Len() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return len(c.entries)
+}
+
+// Purge is used to completely clear the cache.
+func (c *RandomCache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.items = make(map[interface{}]int, c.size)
+	// Clear the slots so the backing array keeps no purged entry alive
+	clear(c.entries)
+	c.entries = c.entries[:0]
+}
+
+// victim samples sampleK entries and returns the index of the least
+// recently used one.
+func (c *RandomCache) victim() int {
+	best := c.rand.Intn(len(c.entries))
+	for i := 1; i < c.sampleK; i++ {
+		j := c.rand.Intn(len(c.entries))
+		if c.entries[j].lastUsed < c.entries[best].lastUsed {
+			best = j
+		}
+	}
+	return best
+}
+
+// removeIndex removes the entry at index i by moving the last entry into
+// its place.
+func (c *RandomCache) removeIndex(i int) {
+	last := len(c.entries) - 1
+	delete(c.items, c.entries[i].key)
+	if i != last {
+		c.entries[i] = c.entries[last]
+		c.items[c.entries[i].key] = i
+	}
+	c.entries[last] = nil
+	c.entries = c.entries[:last]
+}
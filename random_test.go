@@ -0,0 +1,131 @@
+package lru
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func BenchmarkRandom_Rand(b *testing.B) {
+	l, err := NewRandom(8192, 5)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+
+	trace := make([]int64, b.N*2)
+	for i := 0; i < b.N*2; i++ {
+		trace[i] = rand.Int63() % 32768
+	}
+
+	b.ResetTimer()
+
+	var hit, miss int
+	for i := 0; i < 2*b.N; i++ {
+		if i%2 == 0 {
+			l.Add(trace[i], trace[i])
+		} else {
+			_, ok := l.Get(trace[i])
+			if ok {
+				hit++
+			} else {
+				miss++
+			}
+		}
+	}
+	b.Logf("hit: %d miss: %d ratio: %f", hit, miss, float64(hit)/float64(miss))
+}
+
+func TestRandom_InvalidParams(t *testing.T) {
+	if _, err := NewRandom(0, 1); err == nil {
+		t.Fatalf("should fail on a zero size")
+	}
+	if _, err := NewRandom(10, 0); err == nil {
+		t.Fatalf("should fail on a zero sample count")
+	}
+}
+
+func TestRandom(t *testing.T) {
+	l, err := NewRandom(128, 1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 256; i++ {
+		evicted := l.Add(i, i)
+		if evicted != (i >= 128) {
+			t.Fatalf("bad evicted at %d: %v", i, evicted)
+		}
+	}
+	if l.Len() != 128 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	for _, k := range l.Keys() {
+		if v, ok := l.Get(k); !ok || v != k {
+			t.Fatalf("bad key: %v, %v", v, ok)
+		}
+	}
+
+	// Removing moves the last entry into the hole, which must stay reachable
+	keys := l.Keys()
+	if !l.Remove(keys[0]) {
+		t.Fatalf("should be contained")
+	}
+	if l.Contains(keys[0]) {
+		t.Fatalf("should not be contained")
+	}
+	if v, ok := l.Peek(keys[len(keys)-1]); !ok || v != keys[len(keys)-1] {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if l.Len() != 127 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+
+	l.Purge()
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if _, ok := l.Get(keys[1]); ok {
+		t.Fatalf("should be purged")
+	}
+}
+
+// Test that sampling evicts the least recently used of the sampled entries
+func TestRandom_Sampling(t *testing.T) {
+	l, err := NewRandom(2, 64)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Get(1)
+	l.Add(3, 3)
+
+	// With 64 samples out of 2 entries, missing the older entry is
+	// vanishingly unlikely
+	if l.Contains(2) {
+		t.Fatalf("2 should have been evicted")
+	}
+	if !l.Contains(1) || !l.Contains(3) {
+		t.Fatalf("1 and 3 should be contained")
+	}
+}
+
+// test that purged entries are not kept reachable by the entry slice
+func TestRandom_PurgeReleases(t *testing.T) {
+	l, err := NewRandom(4, 1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+	}
+	l.Purge()
+	for i, ent := range l.entries[:cap(l.entries)] {
+		if ent != nil {
+			t.Fatalf("slot %d still holds %v", i, ent.key)
+		}
+	}
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
@@ -0,0 +1,216 @@
+package lru
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// HashFunc maps a key to a shard-selecting hash. It must return the same
+// value for equal keys.
+type HashFunc func(key interface{}) uint64
+
+// ShardedCache is a thread-safe fixed size LRU cache that spreads its keys
+// across several independently locked shards. This reduces lock contention
+// under heavy concurrent access, at the cost of recency being tracked per
+// shard rather than globally.
+type ShardedCache struct {
+	shards []*Cache
+	hash   HashFunc
+}
+
+// NewSharded creates a ShardedCache holding totalSize entries split evenly
+// across the given number of shards, using the default hash function.
+func NewSharded(totalSize, shards int, onEvicted func(key interface{}, value interface{})) (*ShardedCache, error) {
+	return NewShardedWithHash(totalSize, shards, DefaultHash, onEvicted)
+}
+
+// NewShardedWithHash creates a ShardedCache that uses the provided hash
+// function to assign keys to shards.
+func NewShardedWithHash(totalSize, shards int, hash HashFunc, onEvicted func(key interface{}, value interface{})) (*ShardedCache, error) {
+	if shards <= 0 {
+		return nil, ErrInvalidShardCount
+	}
+	if totalSize < shards {
+		return nil, ErrInvalidSize
+	}
+	if hash == nil {
+		hash = DefaultHash
+	}
+
+	c := &ShardedCache{
+		shards: make([]*Cache, shards),
+		hash:   hash,
+	}
+	for i := range c.shards {
+		// Spread the remainder over the first shards
+		size := totalSize / shards
+		if i < totalSize%shards {
+			size++
+		}
+		shard, err := NewWithEvict(size, onEvicted)
+		if err != nil {
+			return nil, err
+		}
+		c.shards[i] = shard
+	}
+	return c, nil
+}
+
+// DefaultHash hashes string, integer, float, complex and bool keys directly
+// and falls back to hashing the formatted value of any other key type.
+// Floats that are equal as map keys, such as 0 and -0, hash the same.
+func DefaultHash(key interface{}) uint64 {
+	switch k := key.(type) {
+	case string:
+		return hashString(k)
+	case int:
+		return mixInt(uint64(k))
+	case int8:
+		return mixInt(uint64(k))
+	case int16:
+		return mixInt(uint64(k))
+	case int32:
+		return mixInt(uint64(k))
+	case int64:
+		return mixInt(uint64(k))
+	case uint:
+		return mixInt(uint64(k))
+	case uint8:
+		return mixInt(uint64(k))
+	case uint16:
+		return mixInt(uint64(k))
+	case uint32:
+		return mixInt(uint64(k))
+	case uint64:
+		return mixInt(k)
+	case uintptr:
+		return mixInt(uint64(k))
+	case float32:
+		return hashFloat(float64(k))
+	case float64:
+		return hashFloat(k)
+	case complex64:
+		return hashFloat(float64(real(k))) ^ mixInt(hashFloat(float64(imag(k))))
+	case complex128:
+		return hashFloat(real(k)) ^ mixInt(hashFloat(imag(k)))
+	case bool:
+		if k {
+			return mixInt(1)
+		}
+		return mixInt(0)
+	default:
+		return hashString(fmt.Sprintf("%#v", k))
+	}
+}
+
+// hashString returns the 64-bit FNV-1a hash of s.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// hashFloat hashes the bits of f, first turning -0 into 0 and every NaN into
+// the same NaN, so that keys which compare equal hash the same.
+func hashFloat(f float64) uint64 {
+	switch {
+	case f == 0:
+		f = 0
+	case f != f:
+		f = math.NaN()
+	}
+	return mixInt(math.Float64bits(f))
+}
+
+// mixInt scrambles an integer key so that sequential keys spread across
+// shards (the finalizer from splitmix64).
+func mixInt(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// shard returns the shard responsible for the given key.
+func (c *ShardedCache) shard(key interface{}) *Cache {
+	return c.shards[c.hash(key)%uint64(len(c.shards))]
+}
+
+// Add adds a value to the cache. Returns true if an eviction occurred.
+func (c *ShardedCache) Add(key, value interface{}) (evicted bool) {
+	return c.shard(key).Add(key, value)
+}
+
+// Get looks up a key's value from the cache.
+func (c *ShardedCache) Get(key interface{}) (value interface{}, ok bool) {
+	return c.shard(key).Get(key)
+}
+
+// Contains checks if a key is in the cache, without updating the
+// recent-ness. An expired key is removed from the cache.
+func (c *ShardedCache) Contains(key interface{}) bool {
+	return c.shard(key).Contains(key)
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the "recently used"-ness of the key.
+func (c *ShardedCache) Peek(key interface{}) (value interface{}, ok bool) {
+	return c.shard(key).Peek(key)
+}
+
+// Remove removes the provided key from the cache.
+func (c *ShardedCache) Remove(key interface{}) (present bool) {
+	return c.shard(key).Remove(key)
+}
+
+// Len returns the number of items across all shards.
+func (c *ShardedCache) Len() int {
+	length := 0
+	for _, shard := range c.shards {
+		length += shard.Len()
+	}
+	return length
+}
+
+// Purge is used to completely clear every shard.
+func (c *ShardedCache) Purge() {
+	for _, shard := range c.shards {
+		shard.Purge()
+	}
+}
+
+// Stats returns the hit, miss and eviction counters summed across all
+// shards.
+func (c *ShardedCache) Stats() Stats {
+	var total Stats
+	for _, stats := range c.ShardStats() {
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Evictions += stats.Evictions
+		total.Pinned += stats.Pinned
+	}
+	return total
+}
+
+// ShardStats returns the hit, miss and eviction counters of each shard, to
+// help spot hot shards.
+func (c *ShardedCache) ShardStats() []Stats {
+	stats := make([]Stats, len(c.shards))
+	for i, shard := range c.shards {
+		stats[i] = shard.Stats()
+	}
+	return stats
+}
+
+// ShardLen returns the number of items in each shard, to help spot keys
+// hashing unevenly.
+func (c *ShardedCache) ShardLen() []int {
+	lens := make([]int, len(c.shards))
+	for i, shard := range c.shards {
+		lens[i] = shard.Len()
+	}
+	return lens
+}
@@ -0,0 +1,196 @@
+package lru
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func BenchmarkSharded_Rand(b *testing.B) {
+	l, err := NewSharded(8192, 16, nil)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+
+	trace := make([]int64, b.N*2)
+	for i := 0; i < b.N*2; i++ {
+		trace[i] = rand.Int63() % 32768
+	}
+
+	b.ResetTimer()
+
+	var hit, miss int
+	for i := 0; i < 2*b.N; i++ {
+		if i%2 == 0 {
+			l.Add(trace[i], trace[i])
+		} else {
+			_, ok := l.Get(trace[i])
+			if ok {
+				hit++
+			} else {
+				miss++
+			}
+		}
+	}
+	b.Logf("hit: %d miss: %d ratio: %f", hit, miss, float64(hit)/float64(miss))
+}
+
+func TestSharded(t *testing.T) {
+	evictCounter := 0
+	var mu sync.Mutex
+	onEvicted := func(k interface{}, v interface{}) {
+		mu.Lock()
+		evictCounter++
+		mu.Unlock()
+	}
+	l, err := NewSharded(130, 4, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		l.Add(i, i)
+	}
+	if l.Len() != 100 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	for i := 0; i < 100; i++ {
+		if v, ok := l.Get(i); !ok || v != i {
+			t.Fatalf("bad key: %v", i)
+		}
+		if !l.Contains(i) {
+			t.Fatalf("should contain: %v", i)
+		}
+	}
+
+	if !l.Remove(5) || l.Contains(5) {
+		t.Fatalf("5 should have been removed")
+	}
+
+	l.Purge()
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if evictCounter != 100 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+
+	// Filling every shard should hold exactly the total size
+	for i := 0; i < 10000; i++ {
+		l.Add(i, i)
+	}
+	if l.Len() != 130 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if evictCounter != 100+10000-130 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+}
+
+// test that keys consistently land in the same shard
+func TestSharded_Hash(t *testing.T) {
+	calls := 0
+	hash := func(key interface{}) uint64 {
+		calls++
+		return uint64(key.(int) % 2)
+	}
+	l, err := NewShardedWithHash(4, 2, hash, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 6; i += 2 {
+		l.Add(i, i)
+	}
+	if calls != 3 {
+		t.Fatalf("hash should have been used: %v", calls)
+	}
+	// Only two even keys fit in shard 0
+	if l.Contains(0) || !l.Contains(2) || !l.Contains(4) {
+		t.Fatalf("even keys should share a shard")
+	}
+	if l.shards[1].Len() != 0 {
+		t.Fatalf("odd shard should be empty")
+	}
+
+	if DefaultHash("foo") != DefaultHash("foo") || DefaultHash(42) != DefaultHash(42) {
+		t.Fatalf("default hash should be consistent")
+	}
+}
+
+// test that keys which are equal as map keys land in the same shard
+func TestSharded_HashFloat(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	l, err := NewSharded(64, 8, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(0.0, 1)
+	l.Add(negZero, 2)
+	l.Add(float32(0), 3)
+	l.Add(float32(negZero), 4)
+	l.Add(complex(0, 0), 5)
+	l.Add(complex(negZero, negZero), 6)
+	if l.Len() != 3 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if v, ok := l.Get(0.0); !ok || v != 2 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+
+	if DefaultHash(math.NaN()) != DefaultHash(-math.NaN()) {
+		t.Fatalf("NaNs should hash the same")
+	}
+	if DefaultHash(true) == DefaultHash(false) || DefaultHash(1.5) == DefaultHash(2.5) {
+		t.Fatalf("distinct keys should hash apart")
+	}
+	if allocs := testing.AllocsPerRun(10, func() {
+		DefaultHash(1.5)
+		DefaultHash(true)
+		DefaultHash(complex(1, 2))
+	}); allocs != 0 {
+		t.Fatalf("bad allocs: %v", allocs)
+	}
+}
+
+// test that invalid sizes are rejected
+func TestSharded_Invalid(t *testing.T) {
+	if _, err := NewSharded(10, 0, nil); !errors.Is(err, ErrInvalidShardCount) {
+		t.Fatalf("should have rejected zero shards: %v", err)
+	}
+	if _, err := NewSharded(2, 4, nil); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("should have rejected fewer entries than shards")
+	}
+}
+
+// test that stats and lengths are reported per shard and in total
+func TestSharded_Stats(t *testing.T) {
+	hash := func(key interface{}) uint64 {
+		return uint64(key.(int) % 2)
+	}
+	l, err := NewShardedWithHash(4, 2, hash, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 6; i += 2 {
+		l.Add(i, i)
+	}
+	l.Add(1, 1)
+	l.Get(4)
+	l.Get(1)
+	l.Get(3)
+
+	shardStats := l.ShardStats()
+	if shardStats[0] != (Stats{Hits: 1, Evictions: 1}) || shardStats[1] != (Stats{Hits: 1, Misses: 1}) {
+		t.Fatalf("bad shard stats: %+v", shardStats)
+	}
+	if stats := l.Stats(); stats != (Stats{Hits: 2, Misses: 1, Evictions: 1}) {
+		t.Fatalf("bad stats: %+v", stats)
+	}
+	if lens := l.ShardLen(); len(lens) != 2 || lens[0] != 2 || lens[1] != 1 {
+		t.Fatalf("bad shard lengths: %v", lens)
+	}
+}
@@ -0,0 +1,117 @@
+package simplelru
+
+// Sizer estimates the number of bytes used by a cache entry
+type Sizer func(key interface{}, value interface{}) int64
+
+// LRUBytes implements a non-thread safe LRU cache bounded by the estimated
+// memory used by its entries. The size of each entry is computed by a Sizer
+// when it is added, and again whenever its value is replaced.
+type LRUBytes struct {
+	lru   *WeightedLRU
+	sizer Sizer
+}
+
+// NewLRUBytes constructs an LRUBytes that evicts the oldest entries to keep
+// their estimated total size within maxBytes.
+func NewLRUBytes(maxBytes int64, sizer Sizer, onEvict EvictCallback) (*LRUBytes, error) {
+	if maxBytes <= 0 {
+		return nil, ErrInvalidMaxCost
+	}
+	if sizer == nil {
+		return nil, ErrNoSizer
+	}
+	lru, err := NewWeightedLRU(maxBytes, onEvict)
+	if err != nil {
+		return nil, err
+	}
+	c := &LRUBytes{
+		lru:   lru,
+		sizer: sizer,
+	}
+	return c, nil
+}
+
+// NewLRUBytesWithCostCallback constructs an LRUBytes like NewLRUBytes,
+// reporting every removal to onEvict along with the estimated size that was
+// freed.
+func NewLRUBytesWithCostCallback(maxBytes int64, sizer Sizer, onEvict EvictCallbackWithCost) (*LRUBytes, error) {
+	c, err := NewLRUBytes(maxBytes, sizer, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.lru.onEvictCost = onEvict
+	return c, nil
+}
+
+// Add adds a value to the cache, evicting the oldest entries until the
+// total size fits the budget. An entry whose size alone exceeds the budget
+// is rejected with an error rather than emptying the cache for it, and any
+// existing value for the key is left in place. Returns true if an eviction
+// occurred.
+func (c *LRUBytes) Add(key, value interface{}) (evicted bool, err error) {
+	return c.lru.AddWeighted(key, value, c.sizer(key, value))
+}
+
+// SetOnReject registers a callback invoked whenever Add refuses an entry,
+// along with the reason. A nil callback disables it.
+func (c *LRUBytes) SetOnReject(onReject RejectCallback) {
+	c.lru.SetOnReject(onReject)
+}
+
+// Get looks up a key's value from the cache.
+func (c *LRUBytes) Get(key interface{}) (value interface{}, ok bool) {
+	return c.lru.Get(key)
+}
+
+// Contains checks if a key is in the cache, without updating the
+// recent-ness.
+func (c *LRUBytes) Contains(key interface{}) bool {
+	return c.lru.Contains(key)
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the "recently used"-ness of the key.
+func (c *LRUBytes) Peek(key interface{}) (value interface{}, ok bool) {
+	return c.lru.Peek(key)
+}
+
+// Remove removes the provided key from the cache, returning if the
+// key was contained.
+func (c *LRUBytes) Remove(key interface{}) (present bool) {
+	return c.lru.Remove(key)
+}
+
+// RemoveOldest removes the oldest item from the cache.
+func (c *LRUBytes) RemoveOldest() (key interface{}, value interface{}, ok bool) {
+	return c.lru.RemoveOldest()
+}
+
+// GetOldest returns the oldest entry
+func (c *LRUBytes) GetOldest() (key interface{}, value interface{}, ok bool) {
+	return c.lru.GetOldest()
+}
+
+// Keys returns a slice of the keys in the cache, from oldest to newest.
+func (c *LRUBytes) Keys() []interface{} {
+	return c.lru.Keys()
+}
+
+// Len returns the number of items in the cache.
+func (c *LRUBytes) Len() int {
+	return c.lru.Len()
+}
+
+// Bytes returns the estimated total size of the items in the cache.
+func (c *LRUBytes) Bytes() int64 {
+	return c.lru.Cost()
+}
+
+// MaxBytes returns the size budget of the cache.
+func (c *LRUBytes) MaxBytes() int64 {
+	return c.lru.MaxCost()
+}
+
+// Purge is used to completely clear the cache.
+func (c *LRUBytes) Purge() {
+	c.lru.Purge()
+}
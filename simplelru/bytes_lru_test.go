@@ -0,0 +1,133 @@
+package simplelru
+
+import "testing"
+
+func stringSizer(k interface{}, v interface{}) int64 {
+	return int64(len(k.(string)) + len(v.(string)))
+}
+
+func TestLRUBytes(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		evictCounter++
+	}
+	l, err := NewLRUBytes(20, stringSizer, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, k := range []string{"a", "b", "c", "d"} {
+		if evicted, err := l.Add(k, "1234"); err != nil || evicted {
+			t.Fatalf("should not have an eviction: %v", err)
+		}
+	}
+	if l.Bytes() != 20 || l.Len() != 4 {
+		t.Fatalf("bad bytes or len: %v, %v", l.Bytes(), l.Len())
+	}
+
+	// Replacing a value recomputes its size
+	if evicted, err := l.Add("d", "1"); err != nil || evicted {
+		t.Fatalf("should not have an eviction: %v", err)
+	}
+	if l.Bytes() != 17 {
+		t.Fatalf("bad bytes: %v", l.Bytes())
+	}
+
+	// A larger value evicts the oldest entries until it fits
+	evicted, err := l.Add("e", "123456789")
+	if err != nil || !evicted {
+		t.Fatalf("should have an eviction: %v", err)
+	}
+	if evictCounter != 2 || l.Bytes() != 17 {
+		t.Fatalf("bad evict count or bytes: %v, %v", evictCounter, l.Bytes())
+	}
+	if l.Contains("a") || l.Contains("b") {
+		t.Fatalf("a and b should have been evicted")
+	}
+	if v, ok := l.Get("e"); !ok || v != "123456789" {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+
+	l.Remove("e")
+	if l.Bytes() != 7 {
+		t.Fatalf("bad bytes: %v", l.Bytes())
+	}
+	l.Purge()
+	if l.Bytes() != 0 || l.Len() != 0 {
+		t.Fatalf("bad bytes or len: %v, %v", l.Bytes(), l.Len())
+	}
+}
+
+// Test that an entry larger than the whole budget is rejected without
+// disturbing the cache
+func TestLRUBytes_TooLarge(t *testing.T) {
+	l, err := NewLRUBytes(10, stringSizer, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add("a", "1")
+	l.Add("b", "1")
+	if _, err := l.Add("c", "1234567890"); err == nil {
+		t.Fatalf("should reject an oversized entry")
+	}
+	if _, err := l.Add("a", "1234567890"); err == nil {
+		t.Fatalf("should reject an oversized update")
+	}
+	if l.Len() != 2 || l.Bytes() != 4 || l.Contains("c") {
+		t.Fatalf("cache should be unchanged: %v", l.Keys())
+	}
+	if v, _ := l.Peek("a"); v != "1" {
+		t.Fatalf("existing value should be kept: %v", v)
+	}
+}
+
+func TestNewLRUBytes_Invalid(t *testing.T) {
+	if _, err := NewLRUBytes(0, stringSizer, nil); err == nil {
+		t.Fatalf("should fail on a zero budget")
+	}
+	if _, err := NewLRUBytes(10, nil, nil); err == nil {
+		t.Fatalf("should fail without a sizer")
+	}
+}
+
+func TestLRUBytes_CostCallback(t *testing.T) {
+	freed := map[interface{}]int64{}
+	onEvicted := func(k interface{}, v interface{}, size int64) {
+		freed[k] = size
+	}
+	l, err := NewLRUBytesWithCostCallback(10, stringSizer, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add("a", "1234")
+	l.Add("b", "12")
+	l.Add("c", "1234")
+	if len(freed) != 1 || freed["a"] != 5 {
+		t.Fatalf("bad freed: %v", freed)
+	}
+	l.Remove("b")
+	if len(freed) != 2 || freed["b"] != 3 || l.Bytes() != 5 {
+		t.Fatalf("bad freed: %v, %v", freed, l.Bytes())
+	}
+}
+
+func TestLRUBytes_OnReject(t *testing.T) {
+	l, err := NewLRUBytes(10, stringSizer, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var rejected []interface{}
+	l.SetOnReject(func(k interface{}, v interface{}, reason RejectReason) {
+		if reason != RejectOversized {
+			t.Fatalf("bad reason: %v", reason)
+		}
+		rejected = append(rejected, k)
+	})
+	l.Add("a", "1234")
+	l.Add("b", "1234567890")
+	if len(rejected) != 1 || rejected[0] != "b" {
+		t.Fatalf("bad rejected: %v", rejected)
+	}
+}
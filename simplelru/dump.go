@@ -0,0 +1,61 @@
+package simplelru
+
+import (
+	"encoding/gob"
+	"io"
+	"time"
+)
+
+// dumpEntry is the serialized form of a cache entry
+type dumpEntry[K comparable, V any] struct {
+	Key      K
+	Value    V
+	ExpireAt time.Time
+	TTL      time.Duration
+}
+
+// Dump writes the unexpired entries of the cache to w using gob, from
+// oldest to newest, along with their expiry. Concrete types stored in
+// interface keys or values, as in an LRU, must be registered with
+// gob.Register. The cache is not modified.
+func (c *TypedLRU[K, V]) Dump(w io.Writer) error {
+	enc := gob.NewEncoder(w)
+	for ent := c.evictList.Back(); ent != nil; ent = ent.Prev() {
+		if c.isExpired(ent) {
+			continue
+		}
+		d := dumpEntry[K, V]{Key: ent.key, Value: ent.value, TTL: ent.ttl}
+		if ent.expire != nil {
+			d.ExpireAt = *ent.expire
+		}
+		if err := enc.Encode(&d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads entries written by Dump from r and adds them to the cache in
+// their original order, so the newest dumped entry becomes the most recently
+// used. Entries that expired since they were dumped are skipped. Entries are
+// added as they are read, so an error may leave the cache partially loaded.
+func (c *TypedLRU[K, V]) Load(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	for {
+		var d dumpEntry[K, V]
+		if err := dec.Decode(&d); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		var ex *time.Time
+		if !d.ExpireAt.IsZero() {
+			if !c.clock.Now().Before(d.ExpireAt) {
+				continue
+			}
+			ex = &d.ExpireAt
+		}
+		c.add(d.Key, d.Value, ex, d.TTL)
+	}
+}
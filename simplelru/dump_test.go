@@ -0,0 +1,65 @@
+package simplelru
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// Test that Dump and Load round-trip entries, their order and their expiry
+func TestLRU_DumpLoad(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, "one")
+	l.AddEx(2, "two", time.Minute)
+	l.AddEx(3, "three", 100*time.Millisecond)
+	l.Add(4, "four")
+	l.Get(1)
+
+	var buf bytes.Buffer
+	if err := l.Dump(&buf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if l.Len() != 4 {
+		t.Fatalf("Dump should not modify the cache: %v", l.Len())
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	l2, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := l2.Load(&buf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	keys := l2.Keys()
+	if len(keys) != 3 || keys[0] != 2 || keys[1] != 4 || keys[2] != 1 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	if v, ok := l2.Peek(4); !ok || v != "four" {
+		t.Fatalf("4 should be set to four: %v, %v", v, ok)
+	}
+
+	_, want, _ := l.GetWithExpire(2)
+	if _, got, _ := l2.GetWithExpire(2); !got.Equal(want) {
+		t.Fatalf("bad expiry for 2: %v != %v", got, want)
+	}
+	if _, got, _ := l2.GetWithExpire(1); !got.IsZero() {
+		t.Fatalf("1 should not expire: %v", got)
+	}
+}
+
+// Test that Load reports malformed input
+func TestLRU_Load_Invalid(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := l.Load(bytes.NewBufferString("not gob")); err == nil {
+		t.Fatalf("should have failed to load")
+	}
+}
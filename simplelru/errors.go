@@ -0,0 +1,65 @@
+package simplelru
+
+import "errors"
+
+var (
+	// ErrInvalidSize is returned when a cache is created or resized with a
+	// size that is not positive.
+	ErrInvalidSize = errors.New("Must provide a positive size")
+
+	// ErrInvalidMaxCost is returned when a cost or byte bounded cache is
+	// created with a budget that is not positive.
+	ErrInvalidMaxCost = errors.New("Must provide a positive max cost")
+
+	// ErrNoSizer is returned when a cache that sizes its entries is
+	// created without a Sizer.
+	ErrNoSizer = errors.New("Must provide a sizer")
+
+	// ErrNegativeCost is returned when an entry is added with a negative
+	// cost.
+	ErrNegativeCost = errors.New("Must provide a non-negative cost")
+
+	// ErrCostTooLarge is returned when an entry costs more than the whole
+	// budget of the cache, so it can never fit.
+	ErrCostTooLarge = errors.New("Entry cost exceeds the max cost")
+
+	// ErrEntryCostTooLarge is returned when an entry costs more than the
+	// max entry cost of a WeightedLRU.
+	ErrEntryCostTooLarge = errors.New("Entry cost exceeds the max entry cost")
+
+	// ErrInvalidMaxEntryCost is returned when a WeightedLRU is created with
+	// a max entry cost that is negative or above its max cost.
+	ErrInvalidMaxEntryCost = errors.New("Must provide a max entry cost within the max cost")
+
+	// ErrInvalidWatermark is returned when an LRU is created with a low
+	// watermark ratio outside (0, 1].
+	ErrInvalidWatermark = errors.New("Must provide a low watermark ratio between 0 and 1")
+
+	// ErrInvalidK is returned when an LRUK is created with a k that is not
+	// positive.
+	ErrInvalidK = errors.New("Must provide a positive k")
+
+	// ErrInvalidGCInterval is returned when an LRU with incremental GC is
+	// created with an operation interval that is not positive.
+	ErrInvalidGCInterval = errors.New("Must provide a positive GC interval")
+
+	// ErrInvalidGCScanLimit is returned when an LRU with incremental GC is
+	// created with a scan limit that is not positive.
+	ErrInvalidGCScanLimit = errors.New("Must provide a positive GC scan limit")
+
+	// ErrNotConstructed is returned when JSON is unmarshaled into a cache
+	// that was not created with a constructor.
+	ErrNotConstructed = errors.New("Must construct the cache before unmarshaling into it")
+
+	// ErrInvalidJSONKey is returned when unmarshaling JSON whose key is an
+	// object or array, which cannot be used as a key.
+	ErrInvalidJSONKey = errors.New("Must provide a JSON key that is not an object or array")
+
+	// ErrInvalidGrowMax is returned by SetAutoGrow when the max size is
+	// below the current size.
+	ErrInvalidGrowMax = errors.New("Must provide a max size no smaller than the size")
+
+	// ErrInvalidGrowFactor is returned by SetAutoGrow when the grow factor
+	// is not above 1.
+	ErrInvalidGrowFactor = errors.New("Must provide a grow factor above 1")
+)
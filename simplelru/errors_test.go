@@ -0,0 +1,56 @@
+package simplelru
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	if _, err := NewLRU(0, nil); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewLFU(-1, nil); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("err: %v", err)
+	}
+	l, err := NewLRU(2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, _, err := l.TryResize(0); !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("err: %v", err)
+	}
+
+	if _, err := NewWeightedLRU(0, nil); !errors.Is(err, ErrInvalidMaxCost) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewLRUBytes(0, stringSizer, nil); !errors.Is(err, ErrInvalidMaxCost) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewLRUBytes(10, nil, nil); !errors.Is(err, ErrNoSizer) {
+		t.Fatalf("err: %v", err)
+	}
+
+	if _, err := NewLRUWithWatermark(10, 0, nil); !errors.Is(err, ErrInvalidWatermark) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewLRUK(10, 0, nil); !errors.Is(err, ErrInvalidK) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewWeightedLRUWithMaxEntryCost(10, 11, nil); !errors.Is(err, ErrInvalidMaxEntryCost) {
+		t.Fatalf("err: %v", err)
+	}
+
+	w, err := NewWeightedLRUWithMaxEntryCost(10, 4, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := w.AddWeighted(1, 1, -1); !errors.Is(err, ErrNegativeCost) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := w.AddWeighted(1, 1, 11); !errors.Is(err, ErrCostTooLarge) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := w.AddWeighted(1, 1, 5); !errors.Is(err, ErrEntryCostTooLarge) {
+		t.Fatalf("err: %v", err)
+	}
+}
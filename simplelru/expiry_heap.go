@@ -0,0 +1,77 @@
+package simplelru
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryHeap is a min-heap of the entries that have an expiry, soonest
+// first. It lets RemoveExpired visit only the entries that have actually
+// expired instead of scanning the whole cache. Entries expiring at the same
+// instant are ordered by version, so the one written first comes first.
+type expiryHeap[K comparable, V any] []*entry[K, V]
+
+func (h expiryHeap[K, V]) Len() int { return len(h) }
+
+func (h expiryHeap[K, V]) Less(i, j int) bool {
+	if h[i].expire.Equal(*h[j].expire) {
+		return h[i].version < h[j].version
+	}
+	return h[i].expire.Before(*h[j].expire)
+}
+
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[K, V]) Push(x interface{}) {
+	e := x.(*entry[K, V])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap[K, V]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+	return e
+}
+
+// setExpire changes the expiry of an entry, keeping the expiry index in
+// sync. The expiry is capped by the cache's max age, if it has one.
+func (c *TypedLRU[K, V]) setExpire(e *entry[K, V], ex *time.Time) {
+	if c.maxAge > 0 {
+		if limit := e.insertedAt.Add(c.maxAge); ex == nil || ex.After(limit) {
+			ex = &limit
+		}
+	}
+	e.expire = ex
+	switch {
+	case ex == nil:
+		c.unindex(e)
+	case e.index < 0:
+		heap.Push(&c.expiries, e)
+	default:
+		heap.Fix(&c.expiries, e.index)
+	}
+}
+
+// reindex restores the position of an indexed entry whose version changed,
+// which orders it among entries expiring at the same instant.
+func (c *TypedLRU[K, V]) reindex(e *entry[K, V]) {
+	if e.index >= 0 {
+		heap.Fix(&c.expiries, e.index)
+	}
+}
+
+// unindex removes an entry from the expiry index, if it is in it.
+func (c *TypedLRU[K, V]) unindex(e *entry[K, V]) {
+	if e.index >= 0 {
+		heap.Remove(&c.expiries, e.index)
+	}
+}
@@ -0,0 +1,100 @@
+package simplelru
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JSONOptions controls how entries that cannot be encoded or decoded are
+// handled by MarshalJSONWithOptions and UnmarshalJSONWithOptions.
+type JSONOptions struct {
+	// SkipInvalid drops entries whose key or value fails to marshal, or
+	// whose key decodes to an unhashable JSON object or array, instead of
+	// failing the whole operation.
+	SkipInvalid bool
+}
+
+// jsonEntry is the JSON form of a cache entry
+type jsonEntry struct {
+	Key      json.RawMessage `json:"key"`
+	Value    json.RawMessage `json:"value"`
+	ExpireAt *time.Time      `json:"expireAt,omitempty"`
+}
+
+// MarshalJSON encodes the unexpired entries of the cache as an array of
+// {"key", "value", "expireAt"} objects, from oldest to newest. Keys and
+// values must be JSON-serializable. The cache is not modified.
+func (c *TypedLRU[K, V]) MarshalJSON() ([]byte, error) {
+	return c.MarshalJSONWithOptions(JSONOptions{})
+}
+
+// MarshalJSONWithOptions is like MarshalJSON, but lets the caller skip
+// entries that fail to marshal.
+func (c *TypedLRU[K, V]) MarshalJSONWithOptions(opts JSONOptions) ([]byte, error) {
+	entries := make([]jsonEntry, 0, len(c.items))
+	for ent := c.evictList.Back(); ent != nil; ent = ent.Prev() {
+		if c.isExpired(ent) {
+			continue
+		}
+		key, err := json.Marshal(ent.key)
+		if err == nil {
+			var value []byte
+			if value, err = json.Marshal(ent.value); err == nil {
+				entries = append(entries, jsonEntry{Key: key, Value: value, ExpireAt: ent.expire})
+				continue
+			}
+		}
+		if !opts.SkipInvalid {
+			return nil, err
+		}
+	}
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON adds the entries encoded by MarshalJSON to the cache in
+// their original order, so the newest encoded entry becomes the most
+// recently used. Entries that have already expired are dropped. The cache
+// must have been constructed first. Keys and values are decoded into K and
+// V, so for an LRU they decode to the generic JSON types and numbers become
+// float64.
+func (c *TypedLRU[K, V]) UnmarshalJSON(data []byte) error {
+	return c.UnmarshalJSONWithOptions(data, JSONOptions{})
+}
+
+// UnmarshalJSONWithOptions is like UnmarshalJSON, but lets the caller skip
+// entries whose key cannot be used in the cache.
+func (c *TypedLRU[K, V]) UnmarshalJSONWithOptions(data []byte, opts JSONOptions) error {
+	if c.items == nil {
+		return ErrNotConstructed
+	}
+	var entries []jsonEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		var key K
+		var value V
+		if err := json.Unmarshal(e.Key, &key); err != nil {
+			return err
+		}
+		switch any(key).(type) {
+		case map[string]interface{}, []interface{}:
+			if opts.SkipInvalid {
+				continue
+			}
+			return ErrInvalidJSONKey
+		}
+		if err := json.Unmarshal(e.Value, &value); err != nil {
+			return err
+		}
+
+		var ttl time.Duration
+		if e.ExpireAt != nil {
+			if ttl = e.ExpireAt.Sub(c.clock.Now()); ttl <= 0 {
+				continue
+			}
+		}
+		c.add(key, value, e.ExpireAt, ttl)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package simplelru
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Test that the JSON encoding round-trips entries, their order and expiry
+func TestLRU_JSON(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add("a", 1)
+	l.AddEx("b", 2, time.Minute)
+	l.AddEx("c", 3, 50*time.Millisecond)
+	l.Add("d", 4)
+	l.Get("a")
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	l2, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := json.Unmarshal(data, l2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	keys := l2.Keys()
+	if len(keys) != 3 || keys[0] != "b" || keys[1] != "d" || keys[2] != "a" {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	if v, ok := l2.Get("a"); !ok || v != float64(1) {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+	if _, expireAt, ok := l2.GetWithExpire("b"); !ok || expireAt.IsZero() {
+		t.Fatalf("b should keep its expiry: %v, %v", expireAt, ok)
+	}
+
+	var zero LRU
+	if err := json.Unmarshal(data, &zero); !errors.Is(err, ErrNotConstructed) {
+		t.Fatalf("should fail on an unconstructed cache")
+	}
+}
+
+// Test that invalid entries can be skipped instead of failing
+func TestLRU_JSONSkipInvalid(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, make(chan int))
+	l.Add(3, 3)
+
+	if _, err := json.Marshal(l); err == nil {
+		t.Fatalf("should fail on an unmarshalable value")
+	}
+	data, err := l.MarshalJSONWithOptions(JSONOptions{SkipInvalid: true})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l2, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := l2.UnmarshalJSON(data); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if l2.Len() != 2 {
+		t.Fatalf("bad len: %v", l2.Len())
+	}
+
+	// Object keys cannot be used in the cache
+	data = []byte(`[{"key":{"a":1},"value":1},{"key":"b","value":2}]`)
+	if err := l2.UnmarshalJSON(data); !errors.Is(err, ErrInvalidJSONKey) {
+		t.Fatalf("should fail on an object key")
+	}
+	l2.Purge()
+	if err := l2.UnmarshalJSONWithOptions(data, JSONOptions{SkipInvalid: true}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := l2.Keys(); len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("bad keys: %v", keys)
+	}
+}
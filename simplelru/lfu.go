@@ -0,0 +1,264 @@
+package simplelru
+
+import (
+	"container/list"
+	"time"
+)
+
+// LFU implements a non-thread safe fixed size LFU cache. On overflow it
+// evicts the entry with the lowest access count, breaking ties by evicting
+// the least recently used of those entries.
+type LFU struct {
+	size     int
+	freqList *list.List // of *freqBucket, in ascending frequency
+	items    map[interface{}]*list.Element
+	expire   time.Duration
+	onEvict  EvictCallback
+}
+
+// freqBucket holds all entries sharing an access count, newest first.
+type freqBucket struct {
+	freq    int
+	entries *list.List
+}
+
+// lfuEntry is used to hold a value in a freqBucket
+type lfuEntry struct {
+	entry[interface{}, interface{}]
+	bucket *list.Element
+}
+
+// NewLFU constructs an LFU of the given size
+func NewLFU(size int, onEvict EvictCallback) (*LFU, error) {
+	return NewLFUWithExpire(size, 0, onEvict)
+}
+
+// NewLFUWithExpire constructs an LFU of the given size whose entries expire
+// after the given duration.
+func NewLFUWithExpire(size int, expire time.Duration, onEvict EvictCallback) (*LFU, error) {
+	if size <= 0 {
+		return nil, ErrInvalidSize
+	}
+	c := &LFU{
+		size:     size,
+		freqList: list.New(),
+		items:    make(map[interface{}]*list.Element),
+		expire:   expire,
+		onEvict:  onEvict,
+	}
+	return c, nil
+}
+
+// Purge is used to completely clear the cache. Entries are reported in
+// the order they would have been evicted.
+func (c *LFU) Purge() {
+	for len(c.items) > 0 {
+		c.removeOldest()
+	}
+}
+
+// Add adds a value to the cache.  Returns true if an eviction occurred.
+func (c *LFU) Add(key, value interface{}) (evicted bool) {
+	return c.AddEx(key, value, 0)
+}
+
+// AddEx adds a value to the cache with a per-key expiry, falling back to
+// the cache's default expiry if expire is not positive. Updating an existing
+// key counts as an access. Returns true if an eviction occurred.
+func (c *LFU) AddEx(key, value interface{}, expire time.Duration) (evicted bool) {
+	var ex *time.Time
+	if expire > 0 {
+		expire := time.Now().Add(expire)
+		ex = &expire
+	} else if c.expire > 0 {
+		expire := time.Now().Add(c.expire)
+		ex = &expire
+	}
+
+	// Check for existing item
+	if ent, ok := c.items[key]; ok {
+		v := ent.Value.(*lfuEntry)
+		v.value = value
+		v.expire = ex
+		c.increment(ent)
+		return false
+	}
+
+	// Make room before inserting, so the new entry is not itself the
+	// least frequently used victim
+	evict := len(c.items) >= c.size
+	if evict {
+		c.removeOldest()
+	}
+
+	// Add new item to the frequency 1 bucket
+	front := c.freqList.Front()
+	if front == nil || front.Value.(*freqBucket).freq != 1 {
+		front = c.freqList.PushFront(&freqBucket{freq: 1, entries: list.New()})
+	}
+	ent := &lfuEntry{entry: entry[interface{}, interface{}]{key: key, value: value, expire: ex}, bucket: front}
+	c.items[key] = front.Value.(*freqBucket).entries.PushFront(ent)
+	return evict
+}
+
+// Get looks up a key's value from the cache, incrementing its access count.
+func (c *LFU) Get(key interface{}) (value interface{}, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		v := ent.Value.(*lfuEntry)
+		if v.IsExpired() {
+			return nil, false
+		}
+		c.increment(ent)
+		return v.value, true
+	}
+	return
+}
+
+// Contains checks if a key is in the cache, without updating its access
+// count or deleting it for being stale.
+func (c *LFU) Contains(key interface{}) bool {
+	if ent, ok := c.items[key]; ok {
+		return !ent.Value.(*lfuEntry).IsExpired()
+	}
+	return false
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the access count of the key.
+func (c *LFU) Peek(key interface{}) (value interface{}, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		v := ent.Value.(*lfuEntry)
+		if v.IsExpired() {
+			return nil, false
+		}
+		return v.value, true
+	}
+	return nil, false
+}
+
+// Remove removes the provided key from the cache, returning if the
+// key was contained.
+func (c *LFU) Remove(key interface{}) (present bool) {
+	if ent, ok := c.items[key]; ok {
+		c.removeElement(ent)
+		return true
+	}
+	return false
+}
+
+// RemoveOldest removes the next entry due for eviction from the cache.
+// Expired entries found along the way are removed as well.
+func (c *LFU) RemoveOldest() (key interface{}, value interface{}, ok bool) {
+	for ent := c.victim(); ent != nil; ent = c.victim() {
+		c.removeElement(ent)
+		kv := ent.Value.(*lfuEntry)
+		if !kv.IsExpired() {
+			return kv.key, kv.value, true
+		}
+	}
+	return nil, nil, false
+}
+
+// GetOldest returns the next entry due for eviction. Expired entries found
+// along the way are removed.
+func (c *LFU) GetOldest() (key interface{}, value interface{}, ok bool) {
+	for ent := c.victim(); ent != nil; ent = c.victim() {
+		kv := ent.Value.(*lfuEntry)
+		if !kv.IsExpired() {
+			return kv.key, kv.value, true
+		}
+		c.removeElement(ent)
+	}
+	return nil, nil, false
+}
+
+// Keys returns a slice of the keys in the cache, in eviction order.
+func (c *LFU) Keys() []interface{} {
+	keys := make([]interface{}, 0, len(c.items))
+	for b := c.freqList.Front(); b != nil; b = b.Next() {
+		entries := b.Value.(*freqBucket).entries
+		for ent := entries.Back(); ent != nil; ent = ent.Prev() {
+			v := ent.Value.(*lfuEntry)
+			if v.IsExpired() {
+				continue
+			}
+			keys = append(keys, v.key)
+		}
+	}
+	return keys
+}
+
+// Len returns the number of items in the cache, including expired items
+// that have not yet been removed.
+func (c *LFU) Len() int {
+	return len(c.items)
+}
+
+// Resize changes the cache size, evicting the oldest entries if it shrinks
+// below the current length, and returns the number evicted along with the
+// previous size. Growing leaves the contents untouched. A non-positive size
+// is rejected like in the constructor and leaves the cache unchanged.
+func (c *LFU) Resize(size int) (evicted, previous int) {
+	previous = c.size
+	if size <= 0 {
+		return 0, previous
+	}
+	diff := c.Len() - size
+	if diff < 0 {
+		diff = 0
+	}
+	for i := 0; i < diff; i++ {
+		c.removeOldest()
+	}
+	c.size = size
+	return diff, previous
+}
+
+// victim returns the least recently used entry of the lowest frequency
+// bucket.
+func (c *LFU) victim() *list.Element {
+	b := c.freqList.Front()
+	if b == nil {
+		return nil
+	}
+	return b.Value.(*freqBucket).entries.Back()
+}
+
+// increment moves an entry to the bucket for its next access count.
+func (c *LFU) increment(e *list.Element) {
+	kv := e.Value.(*lfuEntry)
+	cur := kv.bucket
+	b := cur.Value.(*freqBucket)
+
+	next := cur.Next()
+	if next == nil || next.Value.(*freqBucket).freq != b.freq+1 {
+		next = c.freqList.InsertAfter(&freqBucket{freq: b.freq + 1, entries: list.New()}, cur)
+	}
+	b.entries.Remove(e)
+	if b.entries.Len() == 0 {
+		c.freqList.Remove(cur)
+	}
+	kv.bucket = next
+	c.items[kv.key] = next.Value.(*freqBucket).entries.PushFront(kv)
+}
+
+// removeOldest removes the next entry due for eviction.
+func (c *LFU) removeOldest() {
+	if ent := c.victim(); ent != nil {
+		c.removeElement(ent)
+	}
+}
+
+// removeElement is used to remove a given list element from the cache
+func (c *LFU) removeElement(e *list.Element) {
+	kv := e.Value.(*lfuEntry)
+	b := kv.bucket.Value.(*freqBucket)
+	b.entries.Remove(e)
+	if b.entries.Len() == 0 {
+		c.freqList.Remove(kv.bucket)
+	}
+	delete(c.items, kv.key)
+	if c.onEvict != nil {
+		c.onEvict(kv.key, kv.value)
+	}
+}
@@ -0,0 +1,205 @@
+package simplelru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLFU(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		if k != v {
+			t.Fatalf("Evict values not equal (%v!=%v)", k, v)
+		}
+		evictCounter++
+	}
+	l, err := NewLFU(128, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var _ LRUCache = l
+
+	for i := 0; i < 256; i++ {
+		l.Add(i, i)
+	}
+	if l.Len() != 128 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if evictCounter != 128 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+
+	// With equal frequencies the oldest entries are evicted
+	for i, k := range l.Keys() {
+		if v, ok := l.Peek(k); !ok || v != k || v != i+128 {
+			t.Fatalf("bad key: %v", k)
+		}
+	}
+	for i := 0; i < 128; i++ {
+		if _, ok := l.Get(i); ok {
+			t.Fatalf("should be evicted")
+		}
+	}
+	for i := 128; i < 192; i++ {
+		if !l.Remove(i) {
+			t.Fatalf("should be contained")
+		}
+		if l.Remove(i) {
+			t.Fatalf("should not be contained")
+		}
+	}
+
+	l.Purge()
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if _, ok := l.Get(200); ok {
+		t.Fatalf("should contain nothing")
+	}
+}
+
+// Test that the least frequently used entry is evicted
+func TestLFU_Eviction(t *testing.T) {
+	l, err := NewLFU(3, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	l.Get(1)
+	l.Get(1)
+	l.Get(3)
+
+	// 2 has the lowest frequency
+	if !l.Add(4, 4) {
+		t.Fatalf("should have an eviction")
+	}
+	if l.Contains(2) {
+		t.Fatalf("2 should have been evicted")
+	}
+
+	// 4 is now the only entry with frequency 1
+	l.Add(5, 5)
+	if l.Contains(4) {
+		t.Fatalf("4 should have been evicted")
+	}
+	if !l.Contains(5) {
+		t.Fatalf("new entry should not be evicted on insert")
+	}
+
+	if keys := l.Keys(); len(keys) != 3 || keys[0] != 5 || keys[1] != 3 || keys[2] != 1 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+}
+
+// Test that Peek and Contains don't update the access count
+func TestLFU_PeekContains(t *testing.T) {
+	l, err := NewLFU(2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Get(2)
+	if v, ok := l.Peek(1); !ok || v != 1 {
+		t.Errorf("1 should be set to 1: %v, %v", v, ok)
+	}
+	if !l.Contains(1) {
+		t.Errorf("1 should be contained")
+	}
+
+	l.Add(3, 3)
+	if l.Contains(1) {
+		t.Errorf("should not have updated the access count of 1")
+	}
+}
+
+func TestLFU_GetOldest_RemoveOldest(t *testing.T) {
+	l, err := NewLFU(3, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.AddEx(1, 1, 50*time.Millisecond)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	l.Get(2)
+	time.Sleep(100 * time.Millisecond)
+
+	k, _, ok := l.GetOldest()
+	if !ok || k != 3 {
+		t.Fatalf("bad: %v", k)
+	}
+	if l.Len() != 2 {
+		t.Fatalf("expired entry should have been removed: %v", l.Len())
+	}
+
+	k, _, ok = l.RemoveOldest()
+	if !ok || k != 3 {
+		t.Fatalf("bad: %v", k)
+	}
+	k, _, ok = l.RemoveOldest()
+	if !ok || k != 2 {
+		t.Fatalf("bad: %v", k)
+	}
+	if _, _, ok = l.RemoveOldest(); ok {
+		t.Fatalf("should contain nothing")
+	}
+}
+
+// Test that Resize can upsize and downsize
+func TestLFU_Resize(t *testing.T) {
+	onEvictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		onEvictCounter++
+	}
+	l, err := NewLFU(2, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Get(1)
+	if evicted, previous := l.Resize(1); evicted != 1 || previous != 2 {
+		t.Errorf("1 element should have been evicted: %v, %v", evicted, previous)
+	}
+	if onEvictCounter != 1 || !l.Contains(1) {
+		t.Errorf("2 should have been evicted")
+	}
+	if evicted, previous := l.Resize(2); evicted != 0 || previous != 1 {
+		t.Errorf("0 elements should have been evicted: %v, %v", evicted, previous)
+	}
+	l.Add(3, 3)
+	if !l.Contains(1) || !l.Contains(3) {
+		t.Errorf("Cache should have contained 2 elements")
+	}
+}
+
+// Test that Purge reports entries in eviction order
+func TestLFU_PurgeOrder(t *testing.T) {
+	var evicted []interface{}
+	l, err := NewLFU(10, func(k interface{}, v interface{}) {
+		evicted = append(evicted, k)
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+	l.Get(0)
+	l.Get(0)
+	l.Get(2)
+	l.Purge()
+	for i, k := range []int{1, 3, 4, 2, 0} {
+		if evicted[i] != k {
+			t.Fatalf("bad order: %v", evicted)
+		}
+	}
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
@@ -0,0 +1,96 @@
+package simplelru
+
+// entryList is an intrusive doubly linked list of entries, newest first.
+// The links live in the entries themselves, so unlike container/list it
+// needs no separate element allocation or type assertion per entry.
+type entryList[K comparable, V any] struct {
+	front *entry[K, V]
+	back  *entry[K, V]
+	len   int
+}
+
+// newEntryList returns an empty list.
+func newEntryList[K comparable, V any]() *entryList[K, V] {
+	return &entryList[K, V]{}
+}
+
+// Init clears the list without unlinking its entries.
+func (l *entryList[K, V]) Init() {
+	l.front, l.back, l.len = nil, nil, 0
+}
+
+// Len returns the number of entries in the list.
+func (l *entryList[K, V]) Len() int {
+	return l.len
+}
+
+// Front returns the newest entry, or nil if the list is empty.
+func (l *entryList[K, V]) Front() *entry[K, V] {
+	return l.front
+}
+
+// Back returns the oldest entry, or nil if the list is empty.
+func (l *entryList[K, V]) Back() *entry[K, V] {
+	return l.back
+}
+
+// PushFront inserts e as the newest entry.
+func (l *entryList[K, V]) PushFront(e *entry[K, V]) {
+	e.prev = nil
+	e.next = l.front
+	if l.front != nil {
+		l.front.prev = e
+	} else {
+		l.back = e
+	}
+	l.front = e
+	l.len++
+}
+
+// PushBack inserts e as the oldest entry.
+func (l *entryList[K, V]) PushBack(e *entry[K, V]) {
+	e.next = nil
+	e.prev = l.back
+	if l.back != nil {
+		l.back.next = e
+	} else {
+		l.front = e
+	}
+	l.back = e
+	l.len++
+}
+
+// MoveToFront makes e, which must be in the list, the newest entry.
+func (l *entryList[K, V]) MoveToFront(e *entry[K, V]) {
+	if l.front == e {
+		return
+	}
+	l.Remove(e)
+	l.PushFront(e)
+}
+
+// Remove unlinks e, which must be in the list.
+func (l *entryList[K, V]) Remove(e *entry[K, V]) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		l.front = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		l.back = e.prev
+	}
+	e.next, e.prev = nil, nil
+	l.len--
+}
+
+// Next returns the next older entry, or nil if e is the oldest.
+func (e *entry[K, V]) Next() *entry[K, V] {
+	return e.next
+}
+
+// Prev returns the next newer entry, or nil if e is the newest.
+func (e *entry[K, V]) Prev() *entry[K, V] {
+	return e.prev
+}
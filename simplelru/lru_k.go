@@ -0,0 +1,253 @@
+package simplelru
+
+import (
+	"container/heap"
+	"container/list"
+	"sort"
+)
+
+// LRUK implements a non-thread safe fixed size LRU-K cache. It remembers
+// the last K accesses of each entry and evicts the entry whose Kth most
+// recent access is oldest. Entries seen fewer than K times are always
+// evicted first, least recently used first, so a sequential scan of one-off
+// keys cannot push out entries that are used repeatedly.
+type LRUK struct {
+	size    int
+	k       int
+	clock   uint64 // logical time of the last access
+	items   map[interface{}]*lrukEntry
+	young   *list.List // entries with fewer than k accesses, newest first
+	mature  lrukHeap   // entries with k accesses, by Kth most recent access
+	onEvict EvictCallback
+}
+
+// lrukEntry is used to hold a value and its access history
+type lrukEntry struct {
+	key     interface{}
+	value   interface{}
+	history []uint64      // access times, oldest first, at most k
+	elem    *list.Element // position in young, nil once mature
+	index   int           // position in mature
+}
+
+// NewLRUK constructs an LRUK of the given size that tracks the last k
+// accesses of each entry. A k of 1 behaves like a plain LRU.
+func NewLRUK(size, k int, onEvict EvictCallback) (*LRUK, error) {
+	if size <= 0 {
+		return nil, ErrInvalidSize
+	}
+	if k <= 0 {
+		return nil, ErrInvalidK
+	}
+	c := &LRUK{
+		size:    size,
+		k:       k,
+		items:   make(map[interface{}]*lrukEntry),
+		young:   list.New(),
+		onEvict: onEvict,
+	}
+	return c, nil
+}
+
+// Purge is used to completely clear the cache. Entries are reported in
+// the order they would have been evicted.
+func (c *LRUK) Purge() {
+	for len(c.items) > 0 {
+		c.removeOldest()
+	}
+}
+
+// Add adds a value to the cache, counting as an access of the key. Returns
+// true if an eviction occurred.
+func (c *LRUK) Add(key, value interface{}) (evicted bool) {
+	if ent, ok := c.items[key]; ok {
+		ent.value = value
+		c.access(ent)
+		return false
+	}
+
+	// Make room before inserting, so the new entry is not itself the victim
+	evict := len(c.items) >= c.size
+	if evict {
+		c.removeOldest()
+	}
+
+	ent := &lrukEntry{key: key, value: value, history: make([]uint64, 0, c.k)}
+	ent.elem = c.young.PushFront(ent)
+	c.items[key] = ent
+	c.access(ent)
+	return evict
+}
+
+// Get looks up a key's value from the cache, recording the access.
+func (c *LRUK) Get(key interface{}) (value interface{}, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		c.access(ent)
+		return ent.value, true
+	}
+	return nil, false
+}
+
+// Contains checks if a key is in the cache, without recording an access.
+func (c *LRUK) Contains(key interface{}) bool {
+	_, ok := c.items[key]
+	return ok
+}
+
+// Peek returns the key value (or undefined if not found) without recording
+// an access.
+func (c *LRUK) Peek(key interface{}) (value interface{}, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		return ent.value, true
+	}
+	return nil, false
+}
+
+// Remove removes the provided key from the cache, returning if the
+// key was contained.
+func (c *LRUK) Remove(key interface{}) (present bool) {
+	if ent, ok := c.items[key]; ok {
+		c.removeEntry(ent)
+		return true
+	}
+	return false
+}
+
+// RemoveOldest removes the next entry due for eviction from the cache.
+func (c *LRUK) RemoveOldest() (key interface{}, value interface{}, ok bool) {
+	if ent := c.victim(); ent != nil {
+		c.removeEntry(ent)
+		return ent.key, ent.value, true
+	}
+	return nil, nil, false
+}
+
+// GetOldest returns the next entry due for eviction.
+func (c *LRUK) GetOldest() (key interface{}, value interface{}, ok bool) {
+	if ent := c.victim(); ent != nil {
+		return ent.key, ent.value, true
+	}
+	return nil, nil, false
+}
+
+// Keys returns a slice of the keys in the cache, in eviction order.
+func (c *LRUK) Keys() []interface{} {
+	keys := make([]interface{}, 0, len(c.items))
+	for e := c.young.Back(); e != nil; e = e.Prev() {
+		keys = append(keys, e.Value.(*lrukEntry).key)
+	}
+	mature := make(lrukHeap, len(c.mature))
+	copy(mature, c.mature)
+	sort.Slice(mature, func(i, j int) bool {
+		return mature[i].history[0] < mature[j].history[0]
+	})
+	for _, ent := range mature {
+		keys = append(keys, ent.key)
+	}
+	return keys
+}
+
+// Len returns the number of items in the cache.
+func (c *LRUK) Len() int {
+	return len(c.items)
+}
+
+// Resize changes the cache size, returning the number of entries evicted
+// and the previous size. A non-positive size leaves the cache unchanged.
+func (c *LRUK) Resize(size int) (evicted, previous int) {
+	previous = c.size
+	if size <= 0 {
+		return 0, previous
+	}
+	diff := c.Len() - size
+	if diff < 0 {
+		diff = 0
+	}
+	for i := 0; i < diff; i++ {
+		c.removeOldest()
+	}
+	c.size = size
+	return diff, previous
+}
+
+// access records an access of an entry, moving it to the mature heap once
+// it has been seen k times.
+func (c *LRUK) access(ent *lrukEntry) {
+	c.clock++
+	if len(ent.history) == c.k {
+		copy(ent.history, ent.history[1:])
+		ent.history[c.k-1] = c.clock
+	} else {
+		ent.history = append(ent.history, c.clock)
+	}
+
+	switch {
+	case len(ent.history) < c.k:
+		c.young.MoveToFront(ent.elem)
+	case ent.elem != nil:
+		c.young.Remove(ent.elem)
+		ent.elem = nil
+		heap.Push(&c.mature, ent)
+	default:
+		heap.Fix(&c.mature, ent.index)
+	}
+}
+
+// victim returns the next entry due for eviction.
+func (c *LRUK) victim() *lrukEntry {
+	if e := c.young.Back(); e != nil {
+		return e.Value.(*lrukEntry)
+	}
+	if len(c.mature) > 0 {
+		return c.mature[0]
+	}
+	return nil
+}
+
+// removeOldest removes the next entry due for eviction.
+func (c *LRUK) removeOldest() {
+	if ent := c.victim(); ent != nil {
+		c.removeEntry(ent)
+	}
+}
+
+// removeEntry is used to remove a given entry from the cache
+func (c *LRUK) removeEntry(ent *lrukEntry) {
+	if ent.elem != nil {
+		c.young.Remove(ent.elem)
+	} else {
+		heap.Remove(&c.mature, ent.index)
+	}
+	delete(c.items, ent.key)
+	if c.onEvict != nil {
+		c.onEvict(ent.key, ent.value)
+	}
+}
+
+// lrukHeap is a min-heap of mature entries by their Kth most recent access
+type lrukHeap []*lrukEntry
+
+func (h lrukHeap) Len() int { return len(h) }
+
+func (h lrukHeap) Less(i, j int) bool { return h[i].history[0] < h[j].history[0] }
+
+func (h lrukHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lrukHeap) Push(x interface{}) {
+	ent := x.(*lrukEntry)
+	ent.index = len(*h)
+	*h = append(*h, ent)
+}
+
+func (h *lrukHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	ent := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return ent
+}
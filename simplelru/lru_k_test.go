@@ -0,0 +1,122 @@
+package simplelru
+
+import "testing"
+
+func TestLRUK(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		evictCounter++
+	}
+	l, err := NewLRUK(3, 2, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	l.Get(1)
+	l.Get(2)
+
+	// 3 has been seen once, so it goes before the twice-seen keys
+	if k, _, ok := l.GetOldest(); !ok || k != 3 {
+		t.Fatalf("bad oldest: %v", k)
+	}
+	l.Add(4, 4)
+	if l.Contains(3) || evictCounter != 1 {
+		t.Fatalf("3 should have been evicted")
+	}
+
+	// Among mature keys, the oldest second most recent access goes first
+	l.Get(4)
+	l.Get(1)
+	if keys := l.Keys(); len(keys) != 3 || keys[0] != 2 || keys[1] != 1 || keys[2] != 4 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+
+	if k, v, ok := l.RemoveOldest(); !ok || k != 2 || v != 2 {
+		t.Fatalf("bad: %v, %v, %v", k, v, ok)
+	}
+	if !l.Remove(4) || l.Remove(4) {
+		t.Fatalf("bad remove")
+	}
+	if l.Len() != 1 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+
+	if evicted, previous := l.Resize(1); evicted != 0 || previous != 3 {
+		t.Fatalf("bad resize: %v, %v", evicted, previous)
+	}
+	l.Purge()
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if _, ok := l.Get(1); ok {
+		t.Fatalf("should be purged")
+	}
+}
+
+// Test that LRU-2 keeps repeatedly used keys through a sequential scan that
+// flushes a plain LRU of the same size
+func TestLRUK_ScanResistance(t *testing.T) {
+	lruk, err := NewLRUK(4, 2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	lru, err := NewLRU(4, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, hot := range []int{1, 2} {
+		lruk.Add(hot, hot)
+		lruk.Get(hot)
+		lru.Add(hot, hot)
+		lru.Get(hot)
+	}
+	for i := 100; i < 110; i++ {
+		lruk.Add(i, i)
+		lru.Add(i, i)
+	}
+
+	if !lruk.Contains(1) || !lruk.Contains(2) {
+		t.Fatalf("LRU-K should keep the hot keys: %v", lruk.Keys())
+	}
+	if lru.Contains(1) || lru.Contains(2) {
+		t.Fatalf("plain LRU should have been flushed: %v", lru.Keys())
+	}
+}
+
+func TestLRUK_InvalidParams(t *testing.T) {
+	if _, err := NewLRUK(0, 2, nil); err == nil {
+		t.Fatalf("should fail on a zero size")
+	}
+	if _, err := NewLRUK(2, 0, nil); err == nil {
+		t.Fatalf("should fail on a zero k")
+	}
+}
+
+// Test that Purge reports entries in eviction order
+func TestLRUK_PurgeOrder(t *testing.T) {
+	var evicted []interface{}
+	l, err := NewLRUK(10, 2, func(k interface{}, v interface{}) {
+		evicted = append(evicted, k)
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+	}
+	l.Get(3)
+	l.Get(1)
+	l.Purge()
+	for i, k := range []int{0, 2, 1, 3} {
+		if evicted[i] != k {
+			t.Fatalf("bad order: %v", evicted)
+		}
+	}
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
@@ -0,0 +1,151 @@
+package simplelru
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// EvictionPolicy decides which key a cache evicts when it is full. The
+// cache keeps the values and tells the policy about every key it holds; the
+// policy only orders the keys. Implementations need not be thread-safe.
+type EvictionPolicy interface {
+	// Record notes that key was added to the cache or read from it.
+	Record(key interface{})
+	// Evict chooses the next key to evict and forgets it. It is only
+	// called while the policy holds at least one key.
+	Evict() interface{}
+	// Remove forgets a key that was removed from the cache.
+	Remove(key interface{})
+}
+
+// listPolicy orders keys in a list, newest first, evicting from the back
+type listPolicy struct {
+	keys  *list.List
+	elems map[interface{}]*list.Element
+	touch bool // whether recording a known key moves it to the front
+}
+
+// NewLRUPolicy returns an EvictionPolicy that evicts the least recently
+// recorded key.
+func NewLRUPolicy() EvictionPolicy {
+	return &listPolicy{
+		keys:  list.New(),
+		elems: make(map[interface{}]*list.Element),
+		touch: true,
+	}
+}
+
+// NewFIFOPolicy returns an EvictionPolicy that evicts keys in the order
+// they were first recorded, ignoring later accesses.
+func NewFIFOPolicy() EvictionPolicy {
+	return &listPolicy{
+		keys:  list.New(),
+		elems: make(map[interface{}]*list.Element),
+	}
+}
+
+func (p *listPolicy) Record(key interface{}) {
+	if e, ok := p.elems[key]; ok {
+		if p.touch {
+			p.keys.MoveToFront(e)
+		}
+		return
+	}
+	p.elems[key] = p.keys.PushFront(key)
+}
+
+func (p *listPolicy) Evict() interface{} {
+	key := p.keys.Remove(p.keys.Back())
+	delete(p.elems, key)
+	return key
+}
+
+func (p *listPolicy) Remove(key interface{}) {
+	if e, ok := p.elems[key]; ok {
+		p.keys.Remove(e)
+		delete(p.elems, key)
+	}
+}
+
+// lfuPolicy keeps keys in a min-heap by access count, breaking ties by
+// evicting the least recently recorded key
+type lfuPolicy struct {
+	clock uint64 // logical time of the last Record
+	keys  lfuPolicyHeap
+	items map[interface{}]*lfuPolicyEntry
+}
+
+// lfuPolicyEntry is used to hold a key and its access count in the heap
+type lfuPolicyEntry struct {
+	key      interface{}
+	freq     uint64
+	lastUsed uint64
+	index    int
+}
+
+// NewLFUPolicy returns an EvictionPolicy that evicts the least frequently
+// recorded key, and the least recently recorded of those on a tie.
+func NewLFUPolicy() EvictionPolicy {
+	return &lfuPolicy{
+		items: make(map[interface{}]*lfuPolicyEntry),
+	}
+}
+
+func (p *lfuPolicy) Record(key interface{}) {
+	p.clock++
+	if ent, ok := p.items[key]; ok {
+		ent.freq++
+		ent.lastUsed = p.clock
+		heap.Fix(&p.keys, ent.index)
+		return
+	}
+	ent := &lfuPolicyEntry{key: key, freq: 1, lastUsed: p.clock}
+	p.items[key] = ent
+	heap.Push(&p.keys, ent)
+}
+
+func (p *lfuPolicy) Evict() interface{} {
+	ent := heap.Pop(&p.keys).(*lfuPolicyEntry)
+	delete(p.items, ent.key)
+	return ent.key
+}
+
+func (p *lfuPolicy) Remove(key interface{}) {
+	if ent, ok := p.items[key]; ok {
+		heap.Remove(&p.keys, ent.index)
+		delete(p.items, key)
+	}
+}
+
+// lfuPolicyHeap is a min-heap of keys by access count, then recency
+type lfuPolicyHeap []*lfuPolicyEntry
+
+func (h lfuPolicyHeap) Len() int { return len(h) }
+
+func (h lfuPolicyHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].lastUsed < h[j].lastUsed
+}
+
+func (h lfuPolicyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuPolicyHeap) Push(x interface{}) {
+	ent := x.(*lfuPolicyEntry)
+	ent.index = len(*h)
+	*h = append(*h, ent)
+}
+
+func (h *lfuPolicyHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	ent := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return ent
+}
@@ -0,0 +1,65 @@
+package simplelru
+
+import "testing"
+
+// evictAll drains a policy, returning its keys in eviction order
+func evictAll(p EvictionPolicy, n int) []interface{} {
+	keys := make([]interface{}, n)
+	for i := range keys {
+		keys[i] = p.Evict()
+	}
+	return keys
+}
+
+func TestLRUPolicy(t *testing.T) {
+	p := NewLRUPolicy()
+	for i := 0; i < 4; i++ {
+		p.Record(i)
+	}
+	p.Record(0)
+	p.Remove(2)
+	for i, k := range evictAll(p, 3) {
+		if k != []int{1, 3, 0}[i] {
+			t.Fatalf("bad eviction order at %d: %v", i, k)
+		}
+	}
+}
+
+func TestFIFOPolicy(t *testing.T) {
+	p := NewFIFOPolicy()
+	for i := 0; i < 4; i++ {
+		p.Record(i)
+	}
+	p.Record(0)
+	p.Remove(2)
+	for i, k := range evictAll(p, 3) {
+		if k != []int{0, 1, 3}[i] {
+			t.Fatalf("bad eviction order at %d: %v", i, k)
+		}
+	}
+}
+
+func TestLFUPolicy(t *testing.T) {
+	p := NewLFUPolicy()
+	for i := 0; i < 4; i++ {
+		p.Record(i)
+	}
+	p.Record(0)
+	p.Record(0)
+	p.Record(3)
+	p.Remove(2)
+	for i, k := range evictAll(p, 3) {
+		if k != []int{1, 3, 0}[i] {
+			t.Fatalf("bad eviction order at %d: %v", i, k)
+		}
+	}
+
+	// Ties are broken by recency
+	p.Record("a")
+	p.Record("b")
+	p.Record("a")
+	p.Record("b")
+	if k := p.Evict(); k != "a" {
+		t.Fatalf("bad tie break: %v", k)
+	}
+}
@@ -1,28 +1,266 @@
 package simplelru
 
 import (
-	"container/list"
-	"errors"
+	"iter"
+	"math"
+	"math/bits"
+	"strings"
+	"sync"
 	"time"
 )
 
 // EvictCallback is used to get a callback when a cache entry is evicted
 type EvictCallback func(key interface{}, value interface{})
 
-// LRU implements a non-thread safe fixed size LRU cache
-type LRU struct {
-	size      int
-	evictList *list.List
-	items     map[interface{}]*list.Element
-	expire    time.Duration
-	onEvict   EvictCallback
+// NoExpire can be passed as the TTL of AddEx and similar methods to add an
+// entry that never expires, even in a cache with a default expiry. It is the
+// smallest Duration, so a TTL computed by subtracting times is never mistaken
+// for it. Every other non-positive TTL, including a small negative one from
+// a deadline that has just passed, falls back to the default; use AddUntil
+// to have a past deadline expire the entry at once.
+const NoExpire time.Duration = math.MinInt64
+
+// Clock is a source of the current time, used to decide when entries
+// expire
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is a Clock that reads the system time
+type realClock struct{}
+
+// Now returns the current system time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// AddCallback is used to get a callback when a value is added to the cache.
+// updated is true if the key was already present and its value replaced.
+type AddCallback func(key interface{}, value interface{}, updated bool)
+
+// EvictReason describes why an entry was removed from the cache
+type EvictReason int
+
+const (
+	// ReasonCapacity means the entry was evicted to make room for another
+	ReasonCapacity EvictReason = iota
+	// ReasonExpired means the entry was removed because it expired
+	ReasonExpired
+	// ReasonRemoved means the entry was removed explicitly
+	ReasonRemoved
+	// ReasonPurged means the entry was removed by Purge
+	ReasonPurged
+	// ReasonResized means the entry was evicted because the cache shrank
+	ReasonResized
+)
+
+// String returns the name of the reason.
+func (r EvictReason) String() string {
+	switch r {
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonExpired:
+		return "expired"
+	case ReasonRemoved:
+		return "removed"
+	case ReasonPurged:
+		return "purged"
+	case ReasonResized:
+		return "resized"
+	}
+	return "unknown"
+}
+
+// TTLClampCallback is used to get a callback when a TTL given for a key is
+// raised to the cache's minimum
+type TTLClampCallback func(key interface{}, requested, clamped time.Duration)
+
+// RejectReason describes why an add was refused
+type RejectReason int
+
+const (
+	// RejectOversized means the entry costs more than the cache accepts
+	RejectOversized RejectReason = iota
+	// RejectInvalidCost means the entry has a negative cost
+	RejectInvalidCost
+	// RejectFrozen means the cache was frozen and accepts no writes
+	RejectFrozen
+)
+
+// String returns the name of the reason.
+func (r RejectReason) String() string {
+	switch r {
+	case RejectOversized:
+		return "oversized"
+	case RejectInvalidCost:
+		return "invalid cost"
+	case RejectFrozen:
+		return "frozen"
+	}
+	return "unknown"
+}
+
+// RejectCallback is used to get a callback when an add is refused, so the
+// entry can be sent elsewhere instead of being lost
+type RejectCallback func(key interface{}, value interface{}, reason RejectReason)
+
+// EvictCallbackWithReason is used to get a callback when a cache entry is
+// evicted, along with the reason it was evicted
+type EvictCallbackWithReason func(key interface{}, value interface{}, reason EvictReason)
+
+// EvictCallbackWithMeta is used to get a callback when a cache entry is
+// evicted, along with the metadata attached to it
+type EvictCallbackWithMeta func(key interface{}, value interface{}, meta interface{})
+
+// LRU implements a non-thread safe fixed size LRU cache. It is the TypedLRU
+// of interface{} keys and values, so any comparable key and any value can
+// be stored, boxed in an interface.
+type LRU = TypedLRU[interface{}, interface{}]
+
+// TypedLRU implements a non-thread safe fixed size LRU cache holding keys
+// of type K and values of type V. Entries, the key map and the eviction
+// list are typed, so storing and reading a key or value neither boxes it in
+// an interface nor needs a type assertion.
+type TypedLRU[K comparable, V any] struct {
+	size          int
+	evictList     *entryList[K, V]
+	items         map[K]*entry[K, V]
+	expire        time.Duration
+	onEvict       func(key K, value V)
+	onEvictReason func(key K, value V, reason EvictReason)
+	onEvictMeta   func(key K, value V, meta interface{})
+	onExpire      func(key K, value V)
+	onAdd         func(key K, value V, updated bool)
+	sliding       bool
+	trackAccess   bool // record read times for PeekWithAccess and Inspect
+	fifo          bool
+	watermark     float64 // fraction of size to evict down to, or 0
+	clock         Clock
+	expiries      expiryHeap[K, V] // entries with an expiry, soonest first
+	version       uint64           // last version given to an entry
+	maxAge        time.Duration
+
+	// gcEvery and gcLimit configure incremental GC: every gcEvery adds or
+	// removes, gcOps being the count so far, up to gcLimit expired entries
+	// are removed. Zero gcEvery disables it.
+	gcEvery int
+	gcLimit int
+	gcOps   int
+
+	// growMax and growFactor let a full cache grow instead of evicting,
+	// up to growMax entries; zero growMax disables it
+	growMax    int
+	growFactor float64
+
+	pinned int // entries exempt from capacity eviction
+
+	evictions uint64 // entries removed by adds to make room
+
+	// minTTL is the floor for TTLs given by callers, and onTTLClamp is
+	// told about the TTLs raised to it
+	minTTL     time.Duration
+	onTTLClamp func(key K, requested, clamped time.Duration)
+
+	// pool recycles the entries of removed items to reduce allocations
+	// under churn
+	pool sync.Pool
+}
+
+// Entry is a key/value pair returned by LRU.Entries
+type Entry = TypedEntry[interface{}, interface{}]
+
+// TypedEntry is a key/value pair returned by Entries
+type TypedEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// EntryView is a read-only copy of an entry returned by LRU.Snapshot
+type EntryView = TypedEntryView[interface{}, interface{}]
+
+// TypedEntryView is a read-only copy of an entry returned by Snapshot.
+// ExpireAt is the zero time for entries that never expire, and Position is
+// the entry's recency rank, starting at 0 for the most recently used.
+type TypedEntryView[K comparable, V any] struct {
+	Key      K
+	Value    V
+	ExpireAt time.Time
+	Position int
+}
+
+// EntryInfo describes a single entry, as returned by LRU.Inspect
+type EntryInfo = TypedEntryInfo[interface{}]
+
+// TypedEntryInfo describes a single entry, as returned by Inspect. ExpireAt
+// is the zero time for entries that never expire, Negative is set for
+// entries added by AddNegative, and LastAccess is as in PeekWithAccess.
+type TypedEntryInfo[V any] struct {
+	Value      V
+	Meta       interface{}
+	Version    uint64
+	Negative   bool
+	ExpireAt   time.Time
+	LastAccess time.Time
+}
+
+// TTLStats summarizes the remaining time to live of the unexpired entries
+// in a cache. The durations only cover entries with an expiry and are zero
+// if there are none. Min, Max and Mean are exact, while the percentiles are
+// estimated from logarithmic buckets and are within 1/16 of the exact
+// value.
+type TTLStats struct {
+	Count    int // entries with an expiry
+	NoExpiry int // entries that never expire
+	Min      time.Duration
+	Max      time.Duration
+	Mean     time.Duration
+	P50      time.Duration
+	P90      time.Duration
+	P99      time.Duration
 }
 
 // entry is used to hold a value in the evictList
-type entry struct {
-	key    interface{}
-	value  interface{}
-	expire *time.Time
+type entry[K comparable, V any] struct {
+	key      K
+	value    V
+	expire   *time.Time
+	ttl      time.Duration
+	negative bool
+	meta     interface{}
+	index    int // position in the expiry index, or -1
+
+	// lastAccess is when the entry was last added or read
+	lastAccess time.Time
+
+	// insertedAt is when the entry's value was last added, which the
+	// cache's max age counts from
+	insertedAt time.Time
+
+	// version changes whenever the value does
+	version uint64
+
+	// pinned entries are skipped by capacity eviction
+	pinned bool
+
+	// next and prev link the entry into the evictList
+	next *entry[K, V]
+	prev *entry[K, V]
+}
+
+// newEntry returns an unlinked entry, reusing a released one if there is
+// one.
+func (c *TypedLRU[K, V]) newEntry() *entry[K, V] {
+	if e, ok := c.pool.Get().(*entry[K, V]); ok {
+		return e
+	}
+	return &entry[K, V]{index: -1}
+}
+
+// releaseEntry returns an entry that is no longer referenced by the cache to
+// the pool, zeroing it so it does not keep its key and value alive.
+func (c *TypedLRU[K, V]) releaseEntry(e *entry[K, V]) {
+	*e = entry[K, V]{index: -1}
+	c.pool.Put(e)
 }
 
 // NewLRU constructs an LRU of the given size
@@ -30,200 +268,1473 @@ func NewLRU(size int, onEvict EvictCallback) (*LRU, error) {
 	return NewLRUWithExpire(size, 0, onEvict)
 }
 
+// NewLRUWithExpire constructs an LRU of the given size whose entries expire
+// after the given duration.
 func NewLRUWithExpire(size int, expire time.Duration, onEvict EvictCallback) (*LRU, error) {
+	return NewLRUWithExpireCallback(size, expire, onEvict, nil)
+}
+
+// NewLRUWithSlidingExpire constructs an LRU of the given size whose entries
+// expire after the given duration without being read. Each successful Get
+// pushes an entry's expiry back by the TTL it was added with, so entries
+// added via AddEx slide by their own TTL rather than the default. Peek and
+// Contains do not refresh the expiry.
+func NewLRUWithSlidingExpire(size int, expire time.Duration, onEvict EvictCallback) (*LRU, error) {
+	c, err := NewLRUWithExpire(size, expire, onEvict)
+	if err != nil {
+		return nil, err
+	}
+	c.sliding = true
+	return c, nil
+}
+
+// NewFIFO constructs a cache of the given size that evicts entries in
+// insertion order. It differs from an LRU only in that reads (Get, Touch and
+// friends) do not move an entry to the front, so the next eviction is always
+// the earliest inserted entry. Adding an existing key still counts as a new
+// insertion.
+func NewFIFO(size int, onEvict EvictCallback) (*LRU, error) {
+	return NewFIFOWithExpire(size, 0, onEvict)
+}
+
+// NewFIFOWithExpire constructs a FIFO cache of the given size whose entries
+// expire after the given duration.
+func NewFIFOWithExpire(size int, expire time.Duration, onEvict EvictCallback) (*LRU, error) {
+	c, err := NewLRUWithExpire(size, expire, onEvict)
+	if err != nil {
+		return nil, err
+	}
+	c.fifo = true
+	return c, nil
+}
+
+// NewLRUWithExpireCallback constructs an LRU of the given size whose entries
+// expire after the given duration. onExpire is invoked instead of onEvict
+// when an entry is removed because it expired; onEvict keeps firing for
+// capacity evictions and manual removals. If onExpire is nil, expired
+// entries are reported to onEvict.
+func NewLRUWithExpireCallback(size int, expire time.Duration, onEvict, onExpire EvictCallback) (*LRU, error) {
+	return newTypedLRU[interface{}, interface{}](size, expire, onEvict, onExpire)
+}
+
+// newTypedLRU constructs a TypedLRU of the given size whose entries expire
+// after the given duration, reporting expired entries to onExpire if it is
+// set and all other removals to onEvict.
+func newTypedLRU[K comparable, V any](size int, expire time.Duration, onEvict, onExpire func(key K, value V)) (*TypedLRU[K, V], error) {
 	if size <= 0 {
-		return nil, errors.New("Must provide a positive size")
+		return nil, ErrInvalidSize
 	}
-	c := &LRU{
+	c := &TypedLRU[K, V]{
 		size:      size,
-		evictList: list.New(),
-		items:     make(map[interface{}]*list.Element),
+		evictList: newEntryList[K, V](),
+		items:     make(map[K]*entry[K, V]),
 		expire:    expire,
 		onEvict:   onEvict,
+		onExpire:  onExpire,
+		clock:     realClock{},
+	}
+	return c, nil
+}
+
+// NewLRUWithWatermark constructs an LRU of the given size that, once an
+// insert takes it over capacity, evicts down to lowWatermarkRatio of its
+// size in one batch rather than evicting a single entry on every insert.
+// This amortizes eviction work at steady state, at the cost of the cache
+// holding fewer entries than it could right after a batch. A ratio of 1
+// evicts one entry at a time like NewLRU.
+func NewLRUWithWatermark(size int, lowWatermarkRatio float64, onEvict EvictCallback) (*LRU, error) {
+	if lowWatermarkRatio <= 0 || lowWatermarkRatio > 1 {
+		return nil, ErrInvalidWatermark
+	}
+	c, err := NewLRU(size, onEvict)
+	if err != nil {
+		return nil, err
+	}
+	c.watermark = lowWatermarkRatio
+	return c, nil
+}
+
+// NewLRUWithIncrementalGC constructs an LRU of the given size whose entries
+// expire after the given duration, and which reclaims expired entries
+// without a background goroutine: every everyNOps adds or removes, it
+// removes up to scanLimit expired entries, soonest expiry first. This bounds
+// both how long expired entries linger under steady traffic and the work
+// done by any single operation. The callback can be set with SetOnEvict.
+func NewLRUWithIncrementalGC(size int, expire time.Duration, everyNOps, scanLimit int) (*LRU, error) {
+	if everyNOps <= 0 {
+		return nil, ErrInvalidGCInterval
+	}
+	if scanLimit <= 0 {
+		return nil, ErrInvalidGCScanLimit
+	}
+	c, err := NewLRUWithExpire(size, expire, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.gcEvery = everyNOps
+	c.gcLimit = scanLimit
+	return c, nil
+}
+
+// NewLRUWithReason constructs an LRU of the given size whose entries expire
+// after the given duration, reporting every removal to onEvict along with
+// its reason.
+func NewLRUWithReason(size int, expire time.Duration, onEvict EvictCallbackWithReason) (*LRU, error) {
+	c, err := NewLRUWithExpire(size, expire, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.onEvictReason = onEvict
+	return c, nil
+}
+
+// NewLRUWithMetaCallback constructs an LRU of the given size whose entries
+// expire after the given duration, reporting every removal to onEvict along
+// with the entry's metadata.
+func NewLRUWithMetaCallback(size int, expire time.Duration, onEvict EvictCallbackWithMeta) (*LRU, error) {
+	c, err := NewLRUWithExpire(size, expire, nil)
+	if err != nil {
+		return nil, err
 	}
+	c.onEvictMeta = onEvict
 	return c, nil
 }
 
-func (e *entry) IsExpired() bool {
+func (e *entry[K, V]) IsExpired() bool {
+	return e.expiredAt(time.Now())
+}
+
+// expiredAt reports whether the entry has expired as of now
+func (e *entry[K, V]) expiredAt(now time.Time) bool {
 	if e.expire == nil {
 		return false
 	}
-	return time.Now().After(*e.expire)
+	return now.After(*e.expire)
+}
+
+// isExpired reports whether an entry has expired according to the cache's
+// clock. Every entry with an expiry is in the expiry index, so while the
+// index is empty no entry can have expired and the clock is not consulted,
+// which keeps reads cheap in caches that never use TTLs.
+func (c *TypedLRU[K, V]) isExpired(e *entry[K, V]) bool {
+	if len(c.expiries) == 0 || e.expire == nil {
+		return false
+	}
+	return e.expiredAt(c.clock.Now())
+}
+
+// SetClock replaces the time source used for expiry, which lets tests
+// advance time deterministically. A nil clock restores the real clock.
+func (c *TypedLRU[K, V]) SetClock(clock Clock) {
+	if clock == nil {
+		clock = realClock{}
+	}
+	c.clock = clock
+}
+
+// SetMaxAge caps how long any entry can live, counted from when its value
+// was added, whatever TTL it was given: an entry without an expiry, or with
+// a later one, expires once maxAge has passed since it was added. Sliding
+// expiration cannot extend an entry past the cap either. The cap also
+// applies to the entries already in the cache, but raising it or setting it
+// to zero, which removes it, does not lengthen expiries already capped.
+func (c *TypedLRU[K, V]) SetMaxAge(maxAge time.Duration) {
+	c.maxAge = maxAge
+	if maxAge <= 0 {
+		return
+	}
+	for ent := c.evictList.Front(); ent != nil; ent = ent.Next() {
+		c.setExpire(ent, ent.expire)
+	}
+}
+
+// SetMinTTL sets a floor for the TTLs given to AddEx, GetAndRefresh and
+// SetTTL: a positive TTL below minTTL, such as one computed from a skewed
+// clock, is raised to minTTL instead of expiring the entry almost at once.
+// NoExpire still makes an entry never expire, zero and negative TTLs still
+// fall back to the default expiry, and neither the default expiry nor
+// AddUntil deadlines are affected. A zero minTTL removes the floor.
+func (c *TypedLRU[K, V]) SetMinTTL(minTTL time.Duration) {
+	c.minTTL = minTTL
+}
+
+// SetOnTTLClamp registers a callback invoked whenever a TTL is raised to the
+// minimum set with SetMinTTL, so clamping can be logged or counted. A nil
+// callback disables it.
+func (c *TypedLRU[K, V]) SetOnTTLClamp(onClamp func(key K, requested, clamped time.Duration)) {
+	c.onTTLClamp = onClamp
+}
+
+// SetOnEvict replaces the callback invoked when an entry is removed. A nil
+// callback disables it.
+func (c *TypedLRU[K, V]) SetOnEvict(onEvict func(key K, value V)) {
+	c.onEvict = onEvict
+}
+
+// SetOnExpire replaces the callback invoked instead of the evict callback
+// when an entry is removed because it expired, as in
+// NewLRUWithExpireCallback. A nil callback reports expired entries to the
+// evict callback again.
+func (c *TypedLRU[K, V]) SetOnExpire(onExpire func(key K, value V)) {
+	c.onExpire = onExpire
+}
+
+// SetOnEvictReason replaces the callback invoked with the reason for every
+// removal, as in NewLRUWithReason. A nil callback disables it.
+func (c *TypedLRU[K, V]) SetOnEvictReason(onEvict func(key K, value V, reason EvictReason)) {
+	c.onEvictReason = onEvict
+}
+
+// SetOnEvictMeta replaces the callback invoked with the metadata of every
+// removed entry, as in NewLRUWithMetaCallback. A nil callback disables it.
+func (c *TypedLRU[K, V]) SetOnEvictMeta(onEvict func(key K, value V, meta interface{})) {
+	c.onEvictMeta = onEvict
+}
+
+// SetTrackAccess makes reads record when each entry was last accessed, as
+// reported by PeekWithAccess and Inspect. Recording reads the clock on every
+// hit, so it is off by default and the access time is then when the entry
+// was last added. Caches with sliding expiration always record it.
+func (c *TypedLRU[K, V]) SetTrackAccess(track bool) {
+	c.trackAccess = track
+}
+
+// SetOnAdd registers a callback invoked whenever an add inserts a new key
+// or overwrites an existing one. A nil callback disables it.
+func (c *TypedLRU[K, V]) SetOnAdd(onAdd func(key K, value V, updated bool)) {
+	c.onAdd = onAdd
+}
+
+// Purge is used to completely clear the cache. Entries are reported from
+// oldest to newest; those that had already expired go to the expire
+// callback, if one is configured.
+func (c *TypedLRU[K, V]) Purge() {
+	// Drop the expiry index in one go rather than entry by entry
+	for _, e := range c.expiries {
+		e.index = -1
+	}
+	c.expiries = nil
+
+	// With the index gone isExpired would report nothing, so compare
+	// against the clock directly
+	now := c.clock.Now()
+	var prev *entry[K, V]
+	for ent := c.evictList.Back(); ent != nil; ent = prev {
+		prev = ent.Prev()
+		if ent.expiredAt(now) {
+			c.expireElement(ent)
+		} else {
+			c.removeElement(ent, ReasonPurged)
+		}
+	}
 }
 
-// Purge is used to completely clear the cache.
-func (c *LRU) Purge() {
-	for k, v := range c.items {
-		if c.onEvict != nil {
-			c.onEvict(k, v.Value.(*entry).value)
+// Drain removes every entry from the cache and returns the unexpired ones,
+// from oldest to newest, handing them over to the caller. Unlike Purge, no
+// callbacks are fired, for expired entries either.
+func (c *TypedLRU[K, V]) Drain() []TypedEntryView[K, V] {
+	views := make([]TypedEntryView[K, V], 0, len(c.items))
+	var prev *entry[K, V]
+	for ent := c.evictList.Back(); ent != nil; ent = prev {
+		prev = ent.Prev()
+		if !c.isExpired(ent) {
+			view := TypedEntryView[K, V]{Key: ent.key, Value: ent.value}
+			if ent.expire != nil {
+				view.ExpireAt = *ent.expire
+			}
+			views = append(views, view)
 		}
-		delete(c.items, k)
+		c.releaseEntry(ent)
+	}
+	for i := range views {
+		views[i].Position = len(views) - 1 - i
 	}
+	c.items = make(map[K]*entry[K, V])
 	c.evictList.Init()
+	c.expiries = nil
+	c.pinned = 0
+	return views
 }
 
 // Add adds a value to the cache.  Returns true if an eviction occurred.
-func (c *LRU) Add(key, value interface{}) (evicted bool) {
+func (c *TypedLRU[K, V]) Add(key K, value V) (evicted bool) {
 	return c.AddEx(key, value, 0)
 }
 
-func (c *LRU) AddEx(key, value interface{}, expire time.Duration) (evicted bool) {
-	var ex *time.Time = nil
-	if expire > 0 {
-		expire := time.Now().Add(expire)
-		ex = &expire
-	} else if c.expire > 0 {
-		expire := time.Now().Add(c.expire)
+// AddEx adds a value to the cache with a per-key expiry. A positive expire
+// is used as the entry's TTL, NoExpire makes the entry never expire even if
+// the cache has a default expiry, and any other value, zero or negative,
+// falls back to the cache's default expiry. Returns true if an eviction
+// occurred.
+func (c *TypedLRU[K, V]) AddEx(key K, value V, expire time.Duration) (evicted bool) {
+	var ex *time.Time
+	if expire = c.ttl(key, expire); expire > 0 {
+		expire := c.clock.Now().Add(expire)
 		ex = &expire
 	}
+	return c.add(key, value, ex, expire)
+}
+
+// ttl resolves a TTL passed by the caller for key into the one to use,
+// which is zero for entries that never expire.
+func (c *TypedLRU[K, V]) ttl(key K, expire time.Duration) time.Duration {
+	switch {
+	case expire == NoExpire:
+		return 0
+	case expire <= 0:
+		return c.expire
+	case expire < c.minTTL:
+		if c.onTTLClamp != nil {
+			c.onTTLClamp(key, expire, c.minTTL)
+		}
+		return c.minTTL
+	}
+	return expire
+}
+
+// AddUntil adds a value to the cache that expires at the given deadline,
+// or never if deadline is zero, ignoring the cache's default expiry. An
+// entry whose deadline has already passed is stored but treated as expired
+// from the start. Returns true if an eviction occurred.
+func (c *TypedLRU[K, V]) AddUntil(key K, value V, deadline time.Time) (evicted bool) {
+	if deadline.IsZero() {
+		return c.add(key, value, nil, 0)
+	}
+	ttl := deadline.Sub(c.clock.Now())
+	if ttl < 0 {
+		ttl = 0
+	}
+	return c.add(key, value, &deadline, ttl)
+}
+
+// add inserts or updates an entry with the given expiry time and TTL.
+// Returns true if an eviction occurred.
+func (c *TypedLRU[K, V]) add(key K, value V, ex *time.Time, ttl time.Duration) (evicted bool) {
+	defer c.collect()
 
 	// Check for existing item
+	now := c.clock.Now()
 	if ent, ok := c.items[key]; ok {
 		c.evictList.MoveToFront(ent)
-		v := ent.Value.(*entry)
-		v.value = value
-		v.expire = ex
+		ent.lastAccess = now
+		ent.insertedAt = now
+		c.setValue(ent, value)
+		c.setExpire(ent, ex)
+		ent.ttl = ttl
+		ent.negative = false
+		if c.onAdd != nil {
+			c.onAdd(key, value, true)
+		}
 		return false
 	}
 
 	// Add new item
-	ent := &entry{key, value, ex}
-	entry := c.evictList.PushFront(ent)
-	c.items[key] = entry
+	ent := c.newEntry()
+	ent.key = key
+	c.setValue(ent, value)
+	ent.ttl = ttl
+	ent.lastAccess = now
+	ent.insertedAt = now
+	c.setExpire(ent, ex)
+	c.evictList.PushFront(ent)
+	c.items[key] = ent
 
-	evict := c.evictList.Len() > c.size
-	// Verify size not exceeded
-	if evict {
-		c.removeOldest()
+	if c.evictList.Len() > c.size && c.size < c.growMax {
+		c.grow()
+	}
+	// Verify size not exceeded. When only pinned entries are left the cache
+	// stays over its size.
+	var evict bool
+	if c.evictList.Len() > c.size {
+		for low := c.lowWatermark(); c.evictList.Len() > low; {
+			if !c.removeOldestExcept(ReasonCapacity, ent) {
+				break
+			}
+			c.evictions++
+			evict = true
+		}
+	}
+	if c.onAdd != nil {
+		c.onAdd(key, value, false)
 	}
 	return evict
 }
 
-// Get looks up a key's value from the cache.
-func (c *LRU) Get(key interface{}) (value interface{}, ok bool) {
+// Update replaces the value of an existing key without updating its
+// recent-ness or expiry. Returns false, without inserting, if the key is
+// not in the cache or has expired.
+func (c *TypedLRU[K, V]) Update(key K, value V) (ok bool) {
 	if ent, ok := c.items[key]; ok {
-		v := ent.Value.(*entry)
-		if v.IsExpired() {
-			return nil, false
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return false
 		}
-		c.evictList.MoveToFront(ent)
-		if v == nil {
+		c.setValue(ent, value)
+		ent.negative = false
+		return true
+	}
+	return false
+}
+
+// GetWithVersion looks up a key's value from the cache, updating its
+// recent-ness, along with the version of the value. Pass the version to
+// UpdateIfVersion to replace the value only if nobody changed it since.
+func (c *TypedLRU[K, V]) GetWithVersion(key K) (value V, version uint64, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return value, 0, false
+		}
+		c.promote(ent)
+		c.slide(ent)
+		return ent.value, ent.version, true
+	}
+	return value, 0, false
+}
+
+// UpdateIfVersion replaces the value of an existing key, like Update, but
+// only if its version is still expectedVersion. Returns false if the key is
+// not in the cache, has expired, or its value has changed since.
+func (c *TypedLRU[K, V]) UpdateIfVersion(key K, value V, expectedVersion uint64) (ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return false
+		}
+		if ent.version != expectedVersion {
+			return false
+		}
+		c.setValue(ent, value)
+		ent.negative = false
+		return true
+	}
+	return false
+}
+
+// Renew replaces the value of an existing key and restarts its expiry from
+// the TTL it was added with, without updating its recent-ness. Returns
+// false, without inserting, if the key is not in the cache or has expired.
+func (c *TypedLRU[K, V]) Renew(key K, value V) (ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return false
+		}
+		c.setValue(ent, value)
+		ent.negative = false
+		if ent.ttl > 0 {
+			expire := c.clock.Now().Add(ent.ttl)
+			c.setExpire(ent, &expire)
+		}
+		return true
+	}
+	return false
+}
+
+// Pin exempts an existing key from capacity eviction: adds, Resize and
+// RemoveOldest take the oldest unpinned entry instead, and if every entry is
+// pinned the cache goes over its size rather than rejecting the add, until
+// entries are unpinned or removed. Pinned entries still expire and can still
+// be removed by key. Each eviction skips over the pinned entries older than its
+// victim, so pinning is meant for a small number of entries. Returns false
+// if the key is not in the cache or has expired.
+func (c *TypedLRU[K, V]) Pin(key K) (ok bool) {
+	return c.setPinned(key, true)
+}
+
+// Unpin makes a pinned key subject to capacity eviction again. A cache that
+// went over its size is brought back down by later adds, not by Unpin.
+// Returns false if the key is not in the cache or has expired.
+func (c *TypedLRU[K, V]) Unpin(key K) (ok bool) {
+	return c.setPinned(key, false)
+}
+
+// setPinned implements Pin and Unpin
+func (c *TypedLRU[K, V]) setPinned(key K, pinned bool) bool {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return false
+		}
+		if ent.pinned != pinned {
+			ent.pinned = pinned
+			if pinned {
+				c.pinned++
+			} else {
+				c.pinned--
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// PinnedLen returns the number of pinned entries in the cache, including
+// expired ones that have not been removed yet.
+func (c *TypedLRU[K, V]) PinnedLen() int {
+	return c.pinned
+}
+
+// SetTTL restarts the expiry of an existing key to expire ttl from now,
+// without touching its value or recent-ness. ttl is interpreted as in
+// AddEx, so NoExpire makes the entry never expire, and it also becomes the
+// TTL a sliding expiry renews with. Returns false, without inserting, if
+// the key is not in the cache or has expired.
+func (c *TypedLRU[K, V]) SetTTL(key K, ttl time.Duration) (ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return false
+		}
+		ttl = c.ttl(key, ttl)
+		var ex *time.Time
+		if ttl > 0 {
+			expire := c.clock.Now().Add(ttl)
+			ex = &expire
+		}
+		c.setExpire(ent, ex)
+		ent.ttl = ttl
+		return true
+	}
+	return false
+}
+
+// CompareAndSwap replaces the value of an existing key with new if its
+// current value equals old, updating the key's recent-ness only if the swap
+// happened. The expiry is left as it is. Values are compared with ==, which
+// panics if they are not comparable; use CompareAndSwapFunc for those.
+// Returns false if the key is not in the cache, has expired, or holds a
+// different value.
+func (c *TypedLRU[K, V]) CompareAndSwap(key K, old, new V) (swapped bool) {
+	return c.CompareAndSwapFunc(key, old, new, func(a, b V) bool {
+		return any(a) == any(b)
+	})
+}
+
+// CompareAndSwapFunc is like CompareAndSwap, but compares the current value
+// with old using equal.
+func (c *TypedLRU[K, V]) CompareAndSwapFunc(key K, old, new V, equal func(a, b V) bool) (swapped bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return false
+		}
+		if !equal(ent.value, old) {
+			return false
+		}
+		c.setValue(ent, new)
+		ent.negative = false
+		c.promote(ent)
+		c.slide(ent)
+		return true
+	}
+	return false
+}
+
+// Increment adds delta to the int64 value of an existing key, updating its
+// recent-ness, and returns the new value. The expiry is left as it is.
+// Returns false, without modifying anything, if the key is not in the
+// cache, has expired, or does not hold an int64.
+func (c *TypedLRU[K, V]) Increment(key K, delta int64) (new int64, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return 0, false
+		}
+		n, ok := any(ent.value).(int64)
+		if !ok {
+			return 0, false
+		}
+		n += delta
+		// V holds an int64, so an int64 is always a V
+		c.setValue(ent, any(n).(V))
+		c.promote(ent)
+		c.slide(ent)
+		return n, true
+	}
+	return 0, false
+}
+
+// Decrement subtracts delta from the int64 value of an existing key, like
+// Increment.
+func (c *TypedLRU[K, V]) Decrement(key K, delta int64) (new int64, ok bool) {
+	return c.Increment(key, -delta)
+}
+
+// AddWithMeta adds a value to the cache with a per-key expiry and attaches
+// opaque metadata to it, falling back to the cache's default expiry if
+// expire is not positive. Metadata survives later Add and Update calls for
+// the key until it is replaced by another AddWithMeta. Returns true if an
+// eviction occurred.
+func (c *TypedLRU[K, V]) AddWithMeta(key K, value V, meta interface{}, expire time.Duration) (evicted bool) {
+	evicted = c.AddEx(key, value, expire)
+	c.items[key].meta = meta
+	return evicted
+}
+
+// GetMeta returns the metadata attached to a key without updating its
+// recent-ness. ok is false if the key is not in the cache.
+func (c *TypedLRU[K, V]) GetMeta(key K) (meta interface{}, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
 			return nil, false
 		}
-		return v.value, true
+		return ent.meta, true
 	}
-	return
+	return nil, false
 }
 
-// Contains checks if a key is in the cache, without updating the recent-ness
-// or deleting it for being stale.
-func (c *LRU) Contains(key interface{}) bool {
+// AddNegative caches the absence of a key, typically with a shorter TTL
+// than real values, falling back to the cache's default expiry if negTTL
+// is not positive. A negative entry holds a nil value, so Get reports it as
+// a hit; use GetNegative to tell cached absence apart from a cached value.
+// Adding or updating a value for the key clears the negative mark. Returns true if an
+// eviction occurred.
+func (c *TypedLRU[K, V]) AddNegative(key K, negTTL time.Duration) (evicted bool) {
+	var value V
+	evicted = c.AddEx(key, value, negTTL)
+	c.items[key].negative = true
+	return evicted
+}
+
+// GetNegative looks up a key in the cache, updating its recent-ness, and
+// reports whether it was cached as absent via AddNegative. ok is false if
+// the key is not in the cache at all.
+func (c *TypedLRU[K, V]) GetNegative(key K) (isNegative, ok bool) {
 	if ent, ok := c.items[key]; ok {
-		return !ent.Value.(*entry).IsExpired()
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return false, false
+		}
+		c.promote(ent)
+		c.slide(ent)
+		return ent.negative, true
+	}
+	return false, false
+}
+
+// Get looks up a key's value from the cache. A stored nil value is reported
+// with ok set to true, so ok alone tells a hit from a miss.
+func (c *TypedLRU[K, V]) Get(key K) (value V, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return value, false
+		}
+		c.promote(ent)
+		c.slide(ent)
+		return ent.value, true
+	}
+	return value, false
+}
+
+// GetAndRefresh looks up a key's value from the cache, updating its
+// recent-ness, and restarts its expiry to expire newTTL from now. newTTL is
+// interpreted as in AddEx, so NoExpire makes the entry never expire. The
+// new TTL also becomes the one a sliding expiry renews with. Returns false,
+// without inserting, if the key is not in the cache or has expired.
+func (c *TypedLRU[K, V]) GetAndRefresh(key K, newTTL time.Duration) (value V, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return value, false
+		}
+		newTTL = c.ttl(key, newTTL)
+		var ex *time.Time
+		if newTTL > 0 {
+			expire := c.clock.Now().Add(newTTL)
+			ex = &expire
+		}
+		c.promote(ent)
+		c.setExpire(ent, ex)
+		ent.ttl = newTTL
+		return ent.value, true
+	}
+	return value, false
+}
+
+// GetWithExpire looks up a key's value from the cache, also returning the
+// time at which it expires. The returned time is zero if the key has no
+// expiry, so callers should check expireAt.IsZero().
+func (c *TypedLRU[K, V]) GetWithExpire(key K) (value V, expireAt time.Time, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return value, time.Time{}, false
+		}
+		c.promote(ent)
+		c.slide(ent)
+		if ent.expire != nil {
+			expireAt = *ent.expire
+		}
+		return ent.value, expireAt, true
+	}
+	return value, time.Time{}, false
+}
+
+// Touch marks a key as recently used without reading its value. In a cache
+// using sliding expiration the key's expiry is refreshed as well. Returns
+// whether the key was present and unexpired.
+func (c *TypedLRU[K, V]) Touch(key K) bool {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return false
+		}
+		c.promote(ent)
+		c.slide(ent)
+		return true
+	}
+	return false
+}
+
+// Contains checks if a key is in the cache, without updating the recent-ness.
+// An expired key is removed from the cache.
+func (c *TypedLRU[K, V]) Contains(key K) bool {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return false
+		}
+		return true
 	}
 	return false
 }
 
+// ContainsWithTTL checks if a key is in the cache, without updating the
+// recent-ness, and reports how long until it expires. remaining is -1 for
+// entries that never expire. An expired key is removed from the cache.
+func (c *TypedLRU[K, V]) ContainsWithTTL(key K) (exists bool, remaining time.Duration) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return false, 0
+		}
+		if ent.expire == nil {
+			return true, -1
+		}
+		return true, ent.expire.Sub(c.clock.Now())
+	}
+	return false, 0
+}
+
 // Peek returns the key value (or undefined if not found) without updating
-// the "recently used"-ness of the key.
-func (c *LRU) Peek(key interface{}) (value interface{}, ok bool) {
-	var ent *list.Element
+// the "recently used"-ness of the key. An expired key is removed from the
+// cache.
+func (c *TypedLRU[K, V]) Peek(key K) (value V, ok bool) {
+	var ent *entry[K, V]
 	if ent, ok = c.items[key]; ok {
-		v := ent.Value.(*entry)
-		if v.IsExpired() {
-			return nil, false
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return value, false
+		}
+		return ent.value, true
+	}
+	return value, ok
+}
+
+// PeekWithAccess is like Peek, but also returns when the key was last
+// added or, if tracked as set by SetTrackAccess, read, which shows how hot
+// an entry actually is.
+func (c *TypedLRU[K, V]) PeekWithAccess(key K) (value V, lastAccess time.Time, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return value, time.Time{}, false
 		}
-		return v.value, true
+		return ent.value, ent.lastAccess, true
+	}
+	return value, time.Time{}, false
+}
+
+// GetQuiet returns the key value if it is present and unexpired, leaving
+// the cache exactly as it was: recency and sliding expiry are not updated,
+// and unlike Peek an expired key is not removed.
+func (c *TypedLRU[K, V]) GetQuiet(key K) (value V, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			return value, false
+		}
+		return ent.value, true
+	}
+	return value, false
+}
+
+// Inspect returns everything known about an unexpired key, leaving the
+// cache exactly as it was, like GetQuiet: recency and sliding expiry are
+// not updated, and an expired key is reported missing but not removed.
+func (c *TypedLRU[K, V]) Inspect(key K) (info TypedEntryInfo[V], ok bool) {
+	ent, ok := c.items[key]
+	if !ok || c.isExpired(ent) {
+		return info, false
+	}
+	info = TypedEntryInfo[V]{
+		Value:      ent.value,
+		Meta:       ent.meta,
+		Version:    ent.version,
+		Negative:   ent.negative,
+		LastAccess: ent.lastAccess,
+	}
+	if ent.expire != nil {
+		info.ExpireAt = *ent.expire
+	}
+	return info, true
+}
+
+// PeekRaw returns the stored value of a key even if it has expired, along
+// with whether it has, without modifying the cache. It is meant for
+// monitoring how long expired entries linger; it is the only accessor that
+// returns expired values.
+func (c *TypedLRU[K, V]) PeekRaw(key K) (value V, expired bool, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		return ent.value, c.isExpired(ent), true
 	}
-	return nil, ok
+	return value, false, false
 }
 
 // Remove removes the provided key from the cache, returning if the
 // key was contained.
-func (c *LRU) Remove(key interface{}) (present bool) {
+func (c *TypedLRU[K, V]) Remove(key K) (present bool) {
+	defer c.collect()
 	if ent, ok := c.items[key]; ok {
-		c.removeElement(ent)
+		c.removeElement(ent, ReasonRemoved)
 		return true
 	}
 	return false
 }
 
-// RemoveOldest removes the oldest item from the cache.
-func (c *LRU) RemoveOldest() (key interface{}, value interface{}, ok bool) {
-LOOP:
-	ent := c.evictList.Back()
-	if ent != nil {
-		c.removeElement(ent)
-		kv := ent.Value.(*entry)
-		if kv.IsExpired() {
-			goto LOOP
+// GetDelete looks up a key's value and removes it from the cache, firing
+// the eviction callback with ReasonRemoved. An expired key is removed as
+// expired and reported as missing.
+func (c *TypedLRU[K, V]) GetDelete(key K) (value V, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return value, false
+		}
+		value = ent.value
+		c.removeElement(ent, ReasonRemoved)
+		return value, true
+	}
+	return value, false
+}
+
+// RemoveOldest removes the oldest unexpired item from the cache, skipping
+// pinned entries like capacity eviction does. Expired entries found along
+// the way are removed as well, firing their callbacks, but only the live
+// entry is returned.
+func (c *TypedLRU[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	var prev *entry[K, V]
+	for ent := c.evictList.Back(); ent != nil; ent = prev {
+		prev = ent.Prev()
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			continue
 		}
-		return kv.key, kv.value, true
+		if ent.pinned {
+			continue
+		}
+		key, value = ent.key, ent.value
+		c.removeElement(ent, ReasonRemoved)
+		return key, value, true
 	}
-	return nil, nil, false
+	return key, value, false
+}
+
+// RemoveOldestN removes up to n of the oldest unpinned items from the
+// cache, returning how many were removed. Expired entries found along the
+// way are removed as well but are not counted.
+func (c *TypedLRU[K, V]) RemoveOldestN(n int) (removed int) {
+	for removed < n {
+		if _, _, ok := c.RemoveOldest(); !ok {
+			break
+		}
+		removed++
+	}
+	return removed
 }
 
 // GetOldest returns the oldest entry
-func (c *LRU) GetOldest() (key interface{}, value interface{}, ok bool) {
+func (c *TypedLRU[K, V]) GetOldest() (key K, value V, ok bool) {
 LOOP:
 	ent := c.evictList.Back()
 	if ent != nil {
-		kv := ent.Value.(*entry)
-		if kv.IsExpired() {
-			c.removeElement(ent.Next())
+		if c.isExpired(ent) {
+			c.expireElement(ent)
 			goto LOOP
 		}
-		return kv.key, kv.value, true
+		return ent.key, ent.value, true
 	}
-	return nil, nil, false
+	return key, value, false
 }
 
-// Keys returns a slice of the keys in the cache, from oldest to newest.
-func (c *LRU) Keys() []interface{} {
-	keys := make([]interface{}, 0, len(c.items))
-	var v *entry
+// Clone returns an independent copy of the cache with the same size, expiry
+// settings and callbacks, holding the unexpired entries in the same order
+// and with the same expiry times. Values are copied shallowly, so pointers
+// are shared between the two caches.
+func (c *TypedLRU[K, V]) Clone() *TypedLRU[K, V] {
+	clone := &TypedLRU[K, V]{
+		size:          c.size,
+		evictList:     newEntryList[K, V](),
+		items:         make(map[K]*entry[K, V], len(c.items)),
+		expire:        c.expire,
+		onEvict:       c.onEvict,
+		onEvictReason: c.onEvictReason,
+		onEvictMeta:   c.onEvictMeta,
+		onExpire:      c.onExpire,
+		onAdd:         c.onAdd,
+		sliding:       c.sliding,
+		trackAccess:   c.trackAccess,
+		fifo:          c.fifo,
+		watermark:     c.watermark,
+		clock:         c.clock,
+		version:       c.version,
+		maxAge:        c.maxAge,
+		gcEvery:       c.gcEvery,
+		gcLimit:       c.gcLimit,
+		growMax:       c.growMax,
+		growFactor:    c.growFactor,
+		minTTL:        c.minTTL,
+		onTTLClamp:    c.onTTLClamp,
+	}
+	for ent := c.evictList.Front(); ent != nil; ent = ent.Next() {
+		if c.isExpired(ent) {
+			continue
+		}
+		cp := *ent
+		cp.expire = nil
+		cp.index = -1
+		if ent.expire != nil {
+			expire := *ent.expire
+			clone.setExpire(&cp, &expire)
+		}
+		if cp.pinned {
+			clone.pinned++
+		}
+		clone.evictList.PushBack(&cp)
+		clone.items[cp.key] = &cp
+	}
+	return clone
+}
+
+// RemoveFunc removes every unexpired entry for which pred returns true,
+// returning how many were removed. pred must not modify the cache.
+func (c *TypedLRU[K, V]) RemoveFunc(pred func(key K, value V) bool) (removed int) {
+	var next *entry[K, V]
+	for ent := c.evictList.Back(); ent != nil; ent = next {
+		next = ent.Prev()
+		if c.isExpired(ent) {
+			continue
+		}
+		if pred(ent.key, ent.value) {
+			c.removeElement(ent, ReasonRemoved)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RemovePrefix removes every unexpired entry whose key is a string starting
+// with prefix, firing the eviction callback for each, and returns how many
+// were removed. Keys of other types are never matched.
+func (c *TypedLRU[K, V]) RemovePrefix(prefix string) (removed int) {
+	return c.RemoveFunc(func(key K, value V) bool {
+		s, ok := any(key).(string)
+		return ok && strings.HasPrefix(s, prefix)
+	})
+}
+
+// Map calls f for every unexpired entry, from oldest to newest, storing the
+// value it returns back in place without updating the entry's recent-ness
+// or expiry. If f returns keep as false the entry is removed instead,
+// firing the eviction callback. f must not modify the cache.
+func (c *TypedLRU[K, V]) Map(f func(key K, value V) (newValue V, keep bool)) {
+	var next *entry[K, V]
+	for ent := c.evictList.Back(); ent != nil; ent = next {
+		next = ent.Prev()
+		if c.isExpired(ent) {
+			continue
+		}
+		value, keep := f(ent.key, ent.value)
+		if !keep {
+			c.removeElement(ent, ReasonRemoved)
+			continue
+		}
+		c.setValue(ent, value)
+	}
+}
+
+// Values returns a slice of the values in the cache, from oldest to newest.
+func (c *TypedLRU[K, V]) Values() []V {
+	values := make([]V, 0, len(c.items))
 	for ent := c.evictList.Back(); ent != nil; ent = ent.Prev() {
-		v = ent.Value.(*entry)
-		if v.IsExpired() {
+		if c.isExpired(ent) {
 			continue
 		}
-		keys = append(keys, v.key)
+		values = append(values, ent.value)
+	}
+	return values
+}
+
+// Entries returns a slice of the key/value pairs in the cache, from oldest
+// to newest.
+func (c *TypedLRU[K, V]) Entries() []TypedEntry[K, V] {
+	entries := make([]TypedEntry[K, V], 0, len(c.items))
+	for ent := c.evictList.Back(); ent != nil; ent = ent.Prev() {
+		if c.isExpired(ent) {
+			continue
+		}
+		entries = append(entries, TypedEntry[K, V]{Key: ent.key, Value: ent.value})
+	}
+	return entries
+}
+
+// Snapshot returns a copy of the unexpired entries in the cache with their
+// expiry times, from newest to oldest. It never modifies the cache.
+func (c *TypedLRU[K, V]) Snapshot() []TypedEntryView[K, V] {
+	views := make([]TypedEntryView[K, V], 0, len(c.items))
+	for ent := c.evictList.Front(); ent != nil; ent = ent.Next() {
+		if c.isExpired(ent) {
+			continue
+		}
+		view := TypedEntryView[K, V]{Key: ent.key, Value: ent.value, Position: len(views)}
+		if ent.expire != nil {
+			view.ExpireAt = *ent.expire
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// TTLStats returns a summary of the remaining time to live of the
+// unexpired entries, such as whether many of them expire at about the same
+// time. It makes a single pass over the entries with an expiry and
+// allocates nothing. It never modifies the cache.
+func (c *TypedLRU[K, V]) TTLStats() TTLStats {
+	now := c.clock.Now()
+	stats := TTLStats{NoExpiry: len(c.items) - len(c.expiries)}
+	var buckets [ttlBuckets]int
+	var sum float64
+	for _, ent := range c.expiries {
+		if ent.expiredAt(now) {
+			continue
+		}
+		remaining := ent.expire.Sub(now)
+		if stats.Count == 0 || remaining < stats.Min {
+			stats.Min = remaining
+		}
+		if remaining > stats.Max {
+			stats.Max = remaining
+		}
+		sum += float64(remaining)
+		buckets[ttlBucket(remaining)]++
+		stats.Count++
+	}
+	if stats.Count == 0 {
+		return stats
+	}
+	stats.Mean = time.Duration(sum / float64(stats.Count))
+	percentile := func(p int) time.Duration {
+		rank := (stats.Count - 1) * p / 100
+		for i, n := range buckets {
+			if rank -= n; rank < 0 {
+				d := ttlBucketValue(i)
+				if d < stats.Min {
+					d = stats.Min
+				} else if d > stats.Max {
+					d = stats.Max
+				}
+				return d
+			}
+		}
+		return stats.Max
+	}
+	stats.P50 = percentile(50)
+	stats.P90 = percentile(90)
+	stats.P99 = percentile(99)
+	return stats
+}
+
+// ttlSubBuckets is the number of buckets TTLStats splits each power of two
+// into, 1<<ttlSubBits, which bounds the error of its percentiles.
+const (
+	ttlSubBits    = 3
+	ttlSubBuckets = 1 << ttlSubBits
+)
+
+// ttlBuckets is the number of buckets needed to cover every non-negative
+// Duration.
+const ttlBuckets = 64 * ttlSubBuckets
+
+// ttlBucket returns the TTLStats bucket holding d. Durations below
+// ttlSubBuckets get a bucket each, and every larger power of two is split
+// into ttlSubBuckets buckets of equal width.
+func ttlBucket(d time.Duration) int {
+	if d < ttlSubBuckets {
+		return int(d)
+	}
+	exp := bits.Len64(uint64(d)) - 1
+	sub := int(uint64(d)>>(exp-ttlSubBits)) - ttlSubBuckets
+	return (exp-ttlSubBits+1)*ttlSubBuckets + sub
+}
+
+// ttlBucketValue returns the midpoint of a TTLStats bucket, which is within
+// 1/16 of any duration in it.
+func ttlBucketValue(i int) time.Duration {
+	if i < ttlSubBuckets {
+		return time.Duration(i)
+	}
+	exp := i/ttlSubBuckets + ttlSubBits - 1
+	low := uint64(ttlSubBuckets+i%ttlSubBuckets) << (exp - ttlSubBits)
+	return time.Duration(low + uint64(1)<<(exp-ttlSubBits)/2)
+}
+
+// RemoveExpired removes all expired items from the cache, soonest expiry
+// first, returning how many were removed. It only visits the expired
+// entries, so it costs O(k log n) for k expired entries.
+func (c *TypedLRU[K, V]) RemoveExpired() (removed int) {
+	for len(c.expiries) > 0 && c.isExpired(c.expiries[0]) {
+		c.expireElement(c.items[c.expiries[0].key])
+		removed++
+	}
+	return removed
+}
+
+// collect counts an operation towards incremental GC, removing a bounded
+// number of expired entries when it is due.
+func (c *TypedLRU[K, V]) collect() {
+	if c.gcEvery <= 0 {
+		return
+	}
+	if c.gcOps++; c.gcOps < c.gcEvery {
+		return
+	}
+	c.gcOps = 0
+	for i := 0; i < c.gcLimit && len(c.expiries) > 0 && c.isExpired(c.expiries[0]); i++ {
+		c.expireElement(c.expiries[0])
+	}
+}
+
+// PeekOldest returns the oldest unexpired entry without updating its
+// recent-ness. Unlike GetOldest it never modifies the cache, leaving expired
+// entries in place.
+func (c *TypedLRU[K, V]) PeekOldest() (key K, value V, ok bool) {
+	for ent := c.evictList.Back(); ent != nil; ent = ent.Prev() {
+		if !c.isExpired(ent) {
+			return ent.key, ent.value, true
+		}
+	}
+	return key, value, false
+}
+
+// GetNewest returns the most recently used unexpired entry. Expired entries
+// found along the way are removed.
+func (c *TypedLRU[K, V]) GetNewest() (key K, value V, ok bool) {
+	for ent := c.evictList.Front(); ent != nil; ent = c.evictList.Front() {
+		if !c.isExpired(ent) {
+			return ent.key, ent.value, true
+		}
+		c.expireElement(ent)
+	}
+	return key, value, false
+}
+
+// PeekNewest returns the most recently used unexpired entry without
+// modifying the cache.
+func (c *TypedLRU[K, V]) PeekNewest() (key K, value V, ok bool) {
+	for ent := c.evictList.Front(); ent != nil; ent = ent.Next() {
+		if !c.isExpired(ent) {
+			return ent.key, ent.value, true
+		}
+	}
+	return key, value, false
+}
+
+// Keys returns a slice of the keys in the cache, from oldest to newest.
+// Expired entries found along the way are removed.
+func (c *TypedLRU[K, V]) Keys() []K {
+	keys := make([]K, 0, len(c.items))
+	var next *entry[K, V]
+	for ent := c.evictList.Back(); ent != nil; ent = next {
+		next = ent.Prev()
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			continue
+		}
+		keys = append(keys, ent.key)
 	}
 	return keys
 }
 
-// Len returns the number of items in the cache.
-func (c *LRU) Len() int {
+// KeysPage returns up to limit keys starting at offset in the key ordering,
+// from oldest to newest, along with the total number of unexpired keys.
+// Only the requested window is allocated. It never modifies the cache.
+func (c *TypedLRU[K, V]) KeysPage(offset, limit int) (keys []K, total int) {
+	if limit > 0 {
+		keys = make([]K, 0, limit)
+	}
+	for ent := c.evictList.Back(); ent != nil; ent = ent.Prev() {
+		if c.isExpired(ent) {
+			continue
+		}
+		if total >= offset && len(keys) < limit {
+			keys = append(keys, ent.key)
+		}
+		total++
+	}
+	return keys, total
+}
+
+// Range calls f for each unexpired entry in the cache, from oldest to
+// newest, without updating their recent-ness. Iteration stops early if f
+// returns false. f must not modify the cache.
+func (c *TypedLRU[K, V]) Range(f func(key K, value V) bool) {
+	for ent := c.evictList.Back(); ent != nil; ent = ent.Prev() {
+		if c.isExpired(ent) {
+			continue
+		}
+		if !f(ent.key, ent.value) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the unexpired entries in the cache, from
+// oldest to newest, without updating their recent-ness. The loop body must
+// not modify the cache.
+func (c *TypedLRU[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c.Range(yield)
+	}
+}
+
+// Backward returns an iterator over the unexpired entries in the cache,
+// from newest to oldest, without updating their recent-ness. The loop body
+// must not modify the cache.
+func (c *TypedLRU[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for ent := c.evictList.Front(); ent != nil; ent = ent.Next() {
+			if c.isExpired(ent) {
+				continue
+			}
+			if !yield(ent.key, ent.value) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of items in the cache, including expired items
+// that have not yet been removed.
+func (c *TypedLRU[K, V]) Len() int {
 	return c.evictList.Len()
 }
 
-// Resize changes the cache size.
-func (c *LRU) Resize(size int) (evicted int) {
+// SetAutoGrow makes a full cache grow its size by growFactor instead of
+// evicting when an entry is added, until it reaches maxSize, after which it
+// evicts as usual. Cap reports the size grown to so far. A maxSize of zero
+// disables growth again.
+func (c *TypedLRU[K, V]) SetAutoGrow(maxSize int, growFactor float64) error {
+	if maxSize == 0 {
+		c.growMax = 0
+		return nil
+	}
+	if maxSize < c.size {
+		return ErrInvalidGrowMax
+	}
+	if growFactor <= 1 {
+		return ErrInvalidGrowFactor
+	}
+	c.growMax = maxSize
+	c.growFactor = growFactor
+	return nil
+}
+
+// grow raises the size by the grow factor, by at least one entry and at
+// most up to the max size.
+func (c *TypedLRU[K, V]) grow() {
+	size := int(float64(c.size) * c.growFactor)
+	if size <= c.size {
+		size = c.size + 1
+	}
+	if size > c.growMax {
+		size = c.growMax
+	}
+	c.size = size
+}
+
+// Evictions returns the number of entries removed by adds to make room for
+// new ones since the cache was created. A single add can remove several,
+// such as with NewLRUWithWatermark, while Add only reports whether it
+// removed any. Removals by Resize, Remove or the expiry sweeps are not
+// counted.
+func (c *TypedLRU[K, V]) Evictions() uint64 {
+	return c.evictions
+}
+
+// Cap returns the maximum number of items the cache holds, as last set by
+// the constructor or Resize, or grown to by SetAutoGrow.
+func (c *TypedLRU[K, V]) Cap() int {
+	return c.size
+}
+
+// LenValid returns the number of unexpired items in the cache, without
+// updating their recent-ness.
+func (c *TypedLRU[K, V]) LenValid() int {
+	n := 0
+	for ent := c.evictList.Front(); ent != nil; ent = ent.Next() {
+		if !c.isExpired(ent) {
+			n++
+		}
+	}
+	return n
+}
+
+// Resize changes the cache size, evicting the oldest entries if it shrinks
+// below the current length, and returns the number evicted along with the
+// previous size. Growing leaves the contents untouched. A non-positive size
+// is rejected like in the constructor and leaves the cache unchanged.
+func (c *TypedLRU[K, V]) Resize(size int) (evicted, previous int) {
+	previous = c.size
+	if size <= 0 {
+		return 0, previous
+	}
 	diff := c.Len() - size
 	if diff < 0 {
 		diff = 0
 	}
-	for i := 0; i < diff; i++ {
-		c.removeOldest()
+	evicted = 0
+	for ; evicted < diff; evicted++ {
+		if !c.removeOldest(ReasonResized) {
+			break
+		}
 	}
 	c.size = size
-	return diff
+	return evicted, previous
 }
 
-// removeOldest removes the oldest item from the cache.
-func (c *LRU) removeOldest() {
-	ent := c.evictList.Back()
-	if ent != nil {
-		c.removeElement(ent)
+// lowWatermark returns the length a batch eviction brings the cache down
+// to. The newly added entry is always kept.
+func (c *TypedLRU[K, V]) lowWatermark() int {
+	if c.watermark == 0 {
+		return c.size
+	}
+	low := int(float64(c.size) * c.watermark)
+	if low < 1 {
+		low = 1
 	}
+	return low
 }
 
-// removeElement is used to remove a given list element from the cache
-func (c *LRU) removeElement(e *list.Element) {
+// TryResize is like Resize, but returns an error instead of silently
+// ignoring a non-positive size.
+func (c *TypedLRU[K, V]) TryResize(size int) (evicted, previous int, err error) {
+	if size <= 0 {
+		return 0, c.size, ErrInvalidSize
+	}
+	evicted, previous = c.Resize(size)
+	return evicted, previous, nil
+}
+
+// setValue replaces the value of an entry and gives it a new version.
+// Versions come from a counter shared by the whole cache, so a key that is
+// removed and added again never reuses an old version.
+func (c *TypedLRU[K, V]) setValue(e *entry[K, V], value V) {
+	c.version++
+	e.value = value
+	e.version = c.version
+	c.reindex(e)
+}
+
+// promote records the access of an entry that was just read, if access
+// times are tracked, and moves it to the front of the eviction list, unless
+// the cache evicts in insertion order. Without tracking or a sliding expiry
+// the clock is not read, which keeps hits cheap.
+func (c *TypedLRU[K, V]) promote(e *entry[K, V]) {
+	if c.trackAccess || c.sliding {
+		e.lastAccess = c.clock.Now()
+	}
+	if !c.fifo {
+		c.evictList.MoveToFront(e)
+	}
+}
+
+// slide pushes back the expiry of an entry that was just read, if the cache
+// uses sliding expiration.
+func (c *TypedLRU[K, V]) slide(e *entry[K, V]) {
+	if !c.sliding || e.expire == nil || e.ttl <= 0 {
+		return
+	}
+	expire := c.clock.Now().Add(e.ttl)
+	c.setExpire(e, &expire)
+}
+
+// removeOldest removes the oldest unpinned item from the cache for the
+// given reason, returning false if there is none. Pinned entries are only
+// removed here once they have expired.
+func (c *TypedLRU[K, V]) removeOldest(reason EvictReason) bool {
+	return c.removeOldestExcept(reason, nil)
+}
+
+// removeOldestExcept is like removeOldest, but never removes keep, so an
+// add cannot evict the entry it just added.
+func (c *TypedLRU[K, V]) removeOldestExcept(reason EvictReason, keep *entry[K, V]) bool {
+	for ent := c.evictList.Back(); ent != nil; ent = ent.Prev() {
+		if ent == keep {
+			continue
+		}
+		if c.isExpired(ent) {
+			c.expireElement(ent)
+			return true
+		}
+		if !ent.pinned {
+			c.removeElement(ent, reason)
+			return true
+		}
+	}
+	return false
+}
+
+// removeElement is used to remove a given list element from the cache. The
+// entry is recycled, so callers must not use it afterwards.
+func (c *TypedLRU[K, V]) removeElement(e *entry[K, V], reason EvictReason) {
+	if e.pinned {
+		c.pinned--
+	}
 	c.evictList.Remove(e)
-	kv := e.Value.(*entry)
-	delete(c.items, kv.key)
+	delete(c.items, e.key)
+	c.unindex(e)
+	c.evicted(e, reason)
+	c.releaseEntry(e)
+}
+
+// evicted invokes the eviction callbacks for a removed entry
+func (c *TypedLRU[K, V]) evicted(kv *entry[K, V], reason EvictReason) {
+	if c.onEvictReason != nil {
+		c.onEvictReason(kv.key, kv.value, reason)
+	}
+	if c.onEvictMeta != nil {
+		c.onEvictMeta(kv.key, kv.value, kv.meta)
+	}
 	if c.onEvict != nil {
 		c.onEvict(kv.key, kv.value)
 	}
 }
+
+// expireElement is used to remove a given list element from the cache
+// because it has expired
+func (c *TypedLRU[K, V]) expireElement(e *entry[K, V]) {
+	if c.onExpire == nil {
+		c.removeElement(e, ReasonExpired)
+		return
+	}
+	if e.pinned {
+		c.pinned--
+	}
+	c.evictList.Remove(e)
+	delete(c.items, e.key)
+	c.unindex(e)
+	c.onExpire(e.key, e.value)
+	c.releaseEntry(e)
+}
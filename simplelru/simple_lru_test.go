@@ -1,6 +1,8 @@
 package simplelru
 
 import (
+	"errors"
+	"math"
 	"testing"
 	"time"
 )
@@ -183,13 +185,16 @@ func TestLRU_Resize(t *testing.T) {
 	// Downsize
 	l.Add(1, 1)
 	l.Add(2, 2)
-	evicted := l.Resize(1)
-	if evicted != 1 {
-		t.Errorf("1 element should have been evicted: %v", evicted)
+	evicted, previous := l.Resize(1)
+	if evicted != 1 || previous != 2 {
+		t.Errorf("1 element should have been evicted: %v, %v", evicted, previous)
 	}
 	if onEvictCounter != 1 {
 		t.Errorf("onEvicted should have been called 1 time: %v", onEvictCounter)
 	}
+	if l.Cap() != 1 {
+		t.Errorf("bad cap: %v", l.Cap())
+	}
 
 	l.Add(3, 3)
 	if l.Contains(1) {
@@ -197,9 +202,12 @@ func TestLRU_Resize(t *testing.T) {
 	}
 
 	// Upsize
-	evicted = l.Resize(2)
-	if evicted != 0 {
-		t.Errorf("0 elements should have been evicted: %v", evicted)
+	evicted, previous = l.Resize(2)
+	if evicted != 0 || previous != 1 {
+		t.Errorf("0 elements should have been evicted: %v, %v", evicted, previous)
+	}
+	if l.Cap() != 2 {
+		t.Errorf("bad cap: %v", l.Cap())
 	}
 
 	l.Add(4, 4)
@@ -256,3 +264,2466 @@ func TestSimpleLRU_Expire(t *testing.T) {
 		t.Fatal("not enough keys")
 	}
 }
+
+// Test that LenValid excludes expired entries
+func TestLRU_LenValid(t *testing.T) {
+	l, err := NewLRUWithExpire(10, 50*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, 60*time.Second)
+	if l.LenValid() != 2 {
+		t.Fatalf("bad valid len: %v", l.LenValid())
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if l.LenValid() != 1 {
+		t.Fatalf("bad valid len: %v", l.LenValid())
+	}
+	if l.Len() != 2 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if keys := l.Keys(); len(keys) != 1 || keys[0] != 2 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+}
+
+// Test that RemoveExpired removes only expired entries
+func TestLRU_RemoveExpired(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		evictCounter++
+	}
+	l, err := NewLRUWithExpire(10, 50*time.Millisecond, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.AddEx(3, 3, 60*time.Second)
+	time.Sleep(100 * time.Millisecond)
+
+	if removed := l.RemoveExpired(); removed != 2 {
+		t.Fatalf("bad removed count: %v", removed)
+	}
+	if evictCounter != 2 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+	if l.Len() != 1 || !l.Contains(3) {
+		t.Fatalf("3 should be the only entry")
+	}
+}
+
+// Test that GetWithExpire reports the expiry time of an entry
+func TestLRU_GetWithExpire(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	before := time.Now()
+	l.Add(1, 1)
+	l.AddEx(2, 2, time.Minute)
+	l.AddEx(3, 3, 50*time.Millisecond)
+
+	v, expireAt, ok := l.GetWithExpire(1)
+	if !ok || v != 1 {
+		t.Fatalf("1 should be set to 1: %v, %v", v, ok)
+	}
+	if !expireAt.IsZero() {
+		t.Fatalf("1 should not expire: %v", expireAt)
+	}
+
+	v, expireAt, ok = l.GetWithExpire(2)
+	if !ok || v != 2 {
+		t.Fatalf("2 should be set to 2: %v, %v", v, ok)
+	}
+	if expireAt.Before(before.Add(time.Minute)) || expireAt.After(time.Now().Add(time.Minute)) {
+		t.Fatalf("bad expiry for 2: %v", expireAt)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, _, ok := l.GetWithExpire(3); ok {
+		t.Fatalf("3 should have expired")
+	}
+	if _, _, ok := l.GetWithExpire(4); ok {
+		t.Fatalf("4 should not be contained")
+	}
+}
+
+// Test that GetOldest skips and removes expired entries at the tail
+func TestLRU_GetOldest_Expired(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		l.AddEx(i, i, 50*time.Millisecond)
+	}
+	l.Add(3, 3)
+	l.Add(4, 4)
+	time.Sleep(100 * time.Millisecond)
+
+	k, v, ok := l.GetOldest()
+	if !ok {
+		t.Fatalf("missing")
+	}
+	if k != 3 || v != 3 {
+		t.Fatalf("bad: %v, %v", k, v)
+	}
+	if l.Len() != 2 {
+		t.Fatalf("expired entries should have been removed: %v", l.Len())
+	}
+}
+
+// Test that the expire callback only fires for expired entries
+func TestLRU_ExpireCallback(t *testing.T) {
+	var evicted, expired []interface{}
+	onEvicted := func(k interface{}, v interface{}) {
+		evicted = append(evicted, k)
+	}
+	onExpired := func(k interface{}, v interface{}) {
+		expired = append(expired, k)
+	}
+	l, err := NewLRUWithExpireCallback(3, 0, onEvicted, onExpired)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddEx(1, 1, 50*time.Millisecond)
+	l.AddEx(2, 2, 50*time.Millisecond)
+	l.Add(3, 3)
+	time.Sleep(100 * time.Millisecond)
+
+	// Capacity pressure removes the expired 1
+	l.Add(4, 4)
+	// RemoveOldest skips the expired 2 and removes the live 3
+	if k, _, ok := l.RemoveOldest(); !ok || k != 3 {
+		t.Fatalf("bad: %v", k)
+	}
+	l.Remove(4)
+
+	if len(expired) != 2 || expired[0] != 1 || expired[1] != 2 {
+		t.Fatalf("bad expired: %v", expired)
+	}
+	if len(evicted) != 2 || evicted[0] != 3 || evicted[1] != 4 {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+}
+
+// Test that lazily expired entries are removed and reported exactly once
+func TestLRU_LazyExpireEvicts(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		evictCounter++
+	}
+	l, err := NewLRUWithExpire(10, 50*time.Millisecond, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	time.Sleep(100 * time.Millisecond)
+
+	if _, ok := l.Get(1); ok {
+		t.Fatalf("1 should have expired")
+	}
+	if _, ok := l.Get(1); ok {
+		t.Fatalf("1 should have expired")
+	}
+	if _, ok := l.Peek(2); ok {
+		t.Fatalf("2 should have expired")
+	}
+	if l.Contains(3) || l.Contains(3) {
+		t.Fatalf("3 should have expired")
+	}
+	if evictCounter != 3 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+	if l.Len() != 0 {
+		t.Fatalf("expired entries should have been removed: %v", l.Len())
+	}
+}
+
+// Test that Get refreshes the expiry of sliding entries, but Peek does not
+func TestLRU_SlidingExpire(t *testing.T) {
+	l, err := NewLRUWithSlidingExpire(10, 150*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.AddEx(3, 3, 400*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if _, ok := l.Get(1); !ok {
+			t.Fatalf("1 should have been kept alive by Get")
+		}
+		l.Peek(2)
+	}
+	if l.Contains(2) {
+		t.Fatalf("2 should have expired despite Peek")
+	}
+
+	// 3 slides by its own TTL rather than the default
+	if _, ok := l.Get(3); !ok {
+		t.Fatalf("3 should be contained")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if _, ok := l.Get(3); !ok {
+		t.Fatalf("3 should have slid by its own TTL")
+	}
+}
+
+// Test that Range visits entries from oldest to newest without updating
+// recent-ness
+func TestLRU_Range(t *testing.T) {
+	l, err := NewLRU(3, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, 50*time.Millisecond)
+	l.Add(3, 3)
+	l.Add(4, 4)
+	time.Sleep(100 * time.Millisecond)
+
+	var keys []interface{}
+	l.Range(func(k, v interface{}) bool {
+		if k != v {
+			t.Fatalf("bad value for %v: %v", k, v)
+		}
+		keys = append(keys, k)
+		return true
+	})
+	if len(keys) != 2 || keys[0] != 3 || keys[1] != 4 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+
+	n := 0
+	l.Range(func(k, v interface{}) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Fatalf("Range should have stopped early: %v", n)
+	}
+
+	l.Add(5, 5)
+	l.Add(6, 6)
+	if l.Contains(3) || !l.Contains(4) {
+		t.Fatalf("Range should not have updated recent-ness of 3")
+	}
+}
+
+// Test that Clone copies entries and order into an independent cache
+func TestLRU_Clone(t *testing.T) {
+	l, err := NewLRU(3, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, 50*time.Millisecond)
+	l.AddEx(3, 3, time.Minute)
+	l.Get(1)
+	time.Sleep(100 * time.Millisecond)
+
+	c := l.Clone()
+	if keys := c.Keys(); len(keys) != 2 || keys[0] != 3 || keys[1] != 1 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	_, want, _ := l.GetWithExpire(3)
+	if _, got, _ := c.GetWithExpire(3); !got.Equal(want) {
+		t.Fatalf("bad expiry for 3: %v != %v", got, want)
+	}
+
+	// Mutating one must not affect the other
+	c.Add(4, 4)
+	c.Remove(3)
+	if l.Contains(4) || !l.Contains(3) || !l.Contains(1) {
+		t.Fatalf("original should be unchanged")
+	}
+	l.Add(1, 10)
+	if v, _ := c.Peek(1); v != 1 {
+		t.Fatalf("clone should be unchanged: %v", v)
+	}
+}
+
+// Test that RemoveFunc removes only matching entries
+func TestLRU_RemoveFunc(t *testing.T) {
+	var evicted []interface{}
+	onEvicted := func(k interface{}, v interface{}) {
+		evicted = append(evicted, k)
+	}
+	l, err := NewLRU(10, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		l.Add(i, i)
+	}
+	removed := l.RemoveFunc(func(k, v interface{}) bool {
+		return v.(int)%2 == 0
+	})
+	if removed != 5 {
+		t.Fatalf("bad removed count: %v", removed)
+	}
+	if len(evicted) != 5 || evicted[0] != 0 || evicted[4] != 8 {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+	if keys := l.Keys(); len(keys) != 5 || keys[0] != 1 || keys[4] != 9 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+}
+
+// Test that PeekOldest skips expired entries without modifying the cache
+func TestLRU_PeekOldest(t *testing.T) {
+	l, err := NewLRU(3, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if _, _, ok := l.PeekOldest(); ok {
+		t.Fatalf("should contain nothing")
+	}
+
+	l.AddEx(1, 1, 50*time.Millisecond)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	time.Sleep(100 * time.Millisecond)
+
+	k, v, ok := l.PeekOldest()
+	if !ok || k != 2 || v != 2 {
+		t.Fatalf("bad: %v, %v", k, v)
+	}
+	if l.Len() != 3 {
+		t.Fatalf("PeekOldest should not remove expired entries: %v", l.Len())
+	}
+
+	// 2 is still the oldest live entry, so it is evicted next
+	l.Add(4, 4)
+	l.Add(5, 5)
+	if l.Contains(2) {
+		t.Fatalf("PeekOldest should not have updated recent-ness of 2")
+	}
+}
+
+// Test that GetNewest and PeekNewest return the most recently used entry
+func TestLRU_GetNewest_PeekNewest(t *testing.T) {
+	l, err := NewLRU(3, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if _, _, ok := l.PeekNewest(); ok {
+		t.Fatalf("should contain nothing")
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.AddEx(3, 3, 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	k, v, ok := l.PeekNewest()
+	if !ok || k != 2 || v != 2 {
+		t.Fatalf("bad: %v, %v", k, v)
+	}
+	if l.Len() != 3 {
+		t.Fatalf("PeekNewest should not remove expired entries: %v", l.Len())
+	}
+
+	k, v, ok = l.GetNewest()
+	if !ok || k != 2 || v != 2 {
+		t.Fatalf("bad: %v, %v", k, v)
+	}
+	if l.Len() != 2 {
+		t.Fatalf("GetNewest should remove expired entries: %v", l.Len())
+	}
+
+	l.Get(1)
+	if k, _, _ := l.PeekNewest(); k != 1 {
+		t.Fatalf("bad: %v", k)
+	}
+}
+
+// Test that Values and Entries follow the order of Keys
+func TestLRU_Values_Entries(t *testing.T) {
+	l, err := NewLRU(3, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, "one")
+	l.AddEx(2, "two", 50*time.Millisecond)
+	l.Add(3, "three")
+	l.Get(1)
+	time.Sleep(100 * time.Millisecond)
+
+	values := l.Values()
+	if len(values) != 2 || values[0] != "three" || values[1] != "one" {
+		t.Fatalf("bad values: %v", values)
+	}
+	entries := l.Entries()
+	if len(entries) != 2 || entries[0] != (Entry{3, "three"}) || entries[1] != (Entry{1, "one"}) {
+		t.Fatalf("bad entries: %v", entries)
+	}
+
+	l.Add(4, "four")
+	l.Add(5, "five")
+	if l.Contains(3) {
+		t.Fatalf("Values and Entries should not have updated recent-ness of 3")
+	}
+}
+
+// Test that Update replaces values without updating recent-ness or expiry
+func TestLRU_Update(t *testing.T) {
+	l, err := NewLRU(2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddEx(1, 1, time.Minute)
+	l.Add(2, 2)
+	_, before, _ := l.GetWithExpire(1)
+	l.Add(2, 2)
+
+	if !l.Update(1, 10) {
+		t.Fatalf("1 should have been updated")
+	}
+	if l.Update(3, 3) || l.Contains(3) {
+		t.Fatalf("Update should not insert missing keys")
+	}
+	if v, after, ok := l.GetWithExpire(1); !ok || v != 10 || !after.Equal(before) {
+		t.Fatalf("bad update: %v, %v, %v", v, after, ok)
+	}
+
+	l.Add(2, 2)
+	l.Update(1, 11)
+	l.Add(3, 3)
+	if l.Contains(1) {
+		t.Fatalf("Update should not have updated recent-ness of 1")
+	}
+
+	l.AddEx(4, 4, 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	if l.Update(4, 40) {
+		t.Fatalf("expired 4 should not have been updated")
+	}
+}
+
+// Test that Touch updates recent-ness and refreshes sliding expiry
+func TestLRU_Touch(t *testing.T) {
+	l, err := NewLRUWithSlidingExpire(2, 150*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if !l.Touch(1) {
+		t.Fatalf("1 should have been touched")
+	}
+	if l.Touch(3) {
+		t.Fatalf("3 should not be contained")
+	}
+	l.Add(3, 3)
+	if !l.Contains(1) || l.Contains(2) {
+		t.Fatalf("Touch should have updated recent-ness of 1")
+	}
+
+	for i := 0; i < 3; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if !l.Touch(1) {
+			t.Fatalf("1 should have been kept alive by Touch")
+		}
+	}
+	if l.Touch(3) {
+		t.Fatalf("3 should have expired")
+	}
+}
+
+// Test that negative entries are distinguishable and expire on their own TTL
+func TestLRU_Negative(t *testing.T) {
+	l, err := NewLRUWithExpire(10, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.AddNegative(2, 50*time.Millisecond)
+
+	if isNegative, ok := l.GetNegative(1); !ok || isNegative {
+		t.Fatalf("1 should be a cached value: %v, %v", isNegative, ok)
+	}
+	if isNegative, ok := l.GetNegative(2); !ok || !isNegative {
+		t.Fatalf("2 should be cached as absent: %v, %v", isNegative, ok)
+	}
+	if isNegative, ok := l.GetNegative(3); ok || isNegative {
+		t.Fatalf("3 should be a true miss: %v, %v", isNegative, ok)
+	}
+	if v, ok := l.Get(2); !ok || v != nil {
+		t.Fatalf("negative entries should hold nil: %v, %v", v, ok)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := l.GetNegative(2); ok {
+		t.Fatalf("2 should have expired")
+	}
+
+	// Adding a value clears the negative mark
+	l.AddNegative(3, time.Minute)
+	l.Add(3, 3)
+	if isNegative, ok := l.GetNegative(3); !ok || isNegative {
+		t.Fatalf("3 should be a cached value: %v, %v", isNegative, ok)
+	}
+}
+
+// Test that Keys removes the expired entries it skips
+func TestLRU_KeysRemovesExpired(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		evictCounter++
+	}
+	l, err := NewLRUWithExpire(10, 50*time.Millisecond, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		l.Add(i, i)
+	}
+	time.Sleep(100 * time.Millisecond)
+	l.AddEx(10, 10, time.Minute)
+
+	if keys := l.Keys(); len(keys) != 1 || keys[0] != 10 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	if len(l.items) != 1 || l.evictList.Len() != 1 {
+		t.Fatalf("expired entries should be removed: %d, %d", len(l.items), l.evictList.Len())
+	}
+	// One entry is evicted to make room for 10, the rest are removed by Keys
+	if evictCounter != 10 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+}
+
+// Test that RemoveOldestN trims the oldest entries
+func TestLRU_RemoveOldestN(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		evictCounter++
+	}
+	l, err := NewLRU(10, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+	if removed := l.RemoveOldestN(3); removed != 3 {
+		t.Fatalf("bad removed: %v", removed)
+	}
+	if keys := l.Keys(); len(keys) != 2 || keys[0] != 3 || keys[1] != 4 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	if removed := l.RemoveOldestN(5); removed != 2 {
+		t.Fatalf("bad removed: %v", removed)
+	}
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if evictCounter != 5 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+}
+
+// Test that the reason callback reports why each entry was removed
+func TestLRU_EvictReason(t *testing.T) {
+	reasons := make(map[interface{}]EvictReason)
+	onEvicted := func(k interface{}, v interface{}, reason EvictReason) {
+		reasons[k] = reason
+	}
+	l, err := NewLRUWithReason(3, 0, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	l.Add(4, 4) // evicts 1
+	l.Remove(2)
+	l.AddEx(5, 5, 50*time.Millisecond)
+	l.Resize(2) // evicts 3
+	time.Sleep(100 * time.Millisecond)
+	l.Get(5)
+	l.Purge() // purges 4
+
+	expected := map[interface{}]EvictReason{
+		1: ReasonCapacity,
+		2: ReasonRemoved,
+		3: ReasonResized,
+		4: ReasonPurged,
+		5: ReasonExpired,
+	}
+	for k, want := range expected {
+		if got, ok := reasons[k]; !ok || got != want {
+			t.Errorf("bad reason for %v: %v, want %v", k, got, want)
+		}
+	}
+	if len(reasons) != len(expected) {
+		t.Errorf("bad reasons: %v", reasons)
+	}
+}
+
+// Test that a FIFO cache evicts in insertion order regardless of reads
+func TestLRU_FIFO(t *testing.T) {
+	l, err := NewFIFO(3, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	if v, ok := l.Get(1); !ok || v != 1 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	l.Touch(1)
+
+	l.Add(4, 4)
+	if l.Contains(1) {
+		t.Fatalf("1 should have been evicted despite being read")
+	}
+	if keys := l.Keys(); len(keys) != 3 || keys[0] != 2 || keys[1] != 3 || keys[2] != 4 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+
+	// Re-adding a key counts as a new insertion
+	l.Add(2, 2)
+	l.Add(5, 5)
+	if l.Contains(3) {
+		t.Fatalf("3 should have been evicted")
+	}
+	if !l.Contains(2) {
+		t.Fatalf("2 should be present")
+	}
+}
+
+// Test that metadata is kept across updates and passed on eviction
+func TestLRU_Meta(t *testing.T) {
+	var evictedMeta interface{}
+	onEvicted := func(k interface{}, v interface{}, meta interface{}) {
+		evictedMeta = meta
+	}
+	l, err := NewLRUWithMetaCallback(2, 0, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddWithMeta(1, 1, "source-a", 0)
+	l.Add(2, 2)
+	if meta, ok := l.GetMeta(1); !ok || meta != "source-a" {
+		t.Fatalf("bad meta: %v, %v", meta, ok)
+	}
+	if meta, ok := l.GetMeta(2); !ok || meta != nil {
+		t.Fatalf("bad meta: %v, %v", meta, ok)
+	}
+	if _, ok := l.GetMeta(3); ok {
+		t.Fatalf("3 should not be contained")
+	}
+
+	// Value updates keep the metadata
+	l.Add(1, 10)
+	l.Update(1, 11)
+	if meta, _ := l.GetMeta(1); meta != "source-a" {
+		t.Fatalf("bad meta: %v", meta)
+	}
+	l.AddWithMeta(1, 12, "source-b", 0)
+	if meta, _ := l.GetMeta(1); meta != "source-b" {
+		t.Fatalf("bad meta: %v", meta)
+	}
+
+	l.Remove(1)
+	if evictedMeta != "source-b" {
+		t.Fatalf("bad evicted meta: %v", evictedMeta)
+	}
+}
+
+// Test that Snapshot lists unexpired entries newest first
+func TestLRU_Snapshot(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, time.Minute)
+	l.AddEx(3, 3, 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	l.Add(4, 4)
+
+	views := l.Snapshot()
+	if len(views) != 3 {
+		t.Fatalf("bad views: %v", views)
+	}
+	for i, k := range []int{4, 2, 1} {
+		if views[i].Key != k || views[i].Value != k || views[i].Position != i {
+			t.Fatalf("bad view %d: %v", i, views[i])
+		}
+	}
+	if !views[0].ExpireAt.IsZero() {
+		t.Fatalf("4 should not expire: %v", views[0].ExpireAt)
+	}
+	if remaining := time.Until(views[1].ExpireAt); remaining <= 0 || remaining > time.Minute {
+		t.Fatalf("bad expiry for 2: %v", remaining)
+	}
+
+	// The snapshot is read-only and does not remove expired entries
+	if l.Len() != 4 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
+
+// Test that ContainsWithTTL reports the remaining lifetime
+func TestLRU_ContainsWithTTL(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, time.Minute)
+	l.AddEx(3, 3, 50*time.Millisecond)
+
+	if exists, remaining := l.ContainsWithTTL(1); !exists || remaining != -1 {
+		t.Fatalf("bad: %v, %v", exists, remaining)
+	}
+	if exists, remaining := l.ContainsWithTTL(2); !exists || remaining <= 0 || remaining > time.Minute {
+		t.Fatalf("bad: %v, %v", exists, remaining)
+	}
+	if exists, _ := l.ContainsWithTTL(4); exists {
+		t.Fatalf("4 should not be contained")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if exists, remaining := l.ContainsWithTTL(3); exists || remaining != 0 {
+		t.Fatalf("bad: %v, %v", exists, remaining)
+	}
+	if l.Len() != 2 {
+		t.Fatalf("expired key should be removed: %v", l.Len())
+	}
+}
+
+// Test that Renew replaces a value and restarts its expiry
+func TestLRU_Renew(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddEx(1, 1, 100*time.Millisecond)
+	l.Add(2, 2)
+	time.Sleep(60 * time.Millisecond)
+	if !l.Renew(1, 10) {
+		t.Fatalf("1 should be renewed")
+	}
+	if l.Renew(3, 3) {
+		t.Fatalf("Renew should not insert")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if v, ok := l.Peek(1); !ok || v != 10 {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+	if keys := l.Keys(); keys[0] != 1 {
+		t.Fatalf("Renew should not update recency: %v", keys)
+	}
+}
+
+// Test that RemoveOldest skips over a mix of expired and live entries
+func TestLRU_RemoveOldestMixedTail(t *testing.T) {
+	var evicted []interface{}
+	onEvicted := func(k interface{}, v interface{}) {
+		evicted = append(evicted, k)
+	}
+	l, err := NewLRU(10, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddEx(1, 1, 50*time.Millisecond)
+	l.AddEx(2, 2, 50*time.Millisecond)
+	l.Add(3, 3)
+	l.AddEx(4, 4, 50*time.Millisecond)
+	l.Add(5, 5)
+	time.Sleep(100 * time.Millisecond)
+
+	k, v, ok := l.RemoveOldest()
+	if !ok || k != 3 || v != 3 {
+		t.Fatalf("bad: %v, %v, %v", k, v, ok)
+	}
+	if len(evicted) != 3 || evicted[0] != 1 || evicted[1] != 2 || evicted[2] != 3 {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+
+	k, v, ok = l.RemoveOldest()
+	if !ok || k != 5 || v != 5 {
+		t.Fatalf("bad: %v, %v, %v", k, v, ok)
+	}
+	if len(evicted) != 5 || evicted[3] != 4 {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+
+	if _, _, ok := l.RemoveOldest(); ok {
+		t.Fatalf("cache should be empty")
+	}
+}
+
+// Test that RemoveOldest reports nothing when every entry has expired
+func TestLRU_RemoveOldestAllExpired(t *testing.T) {
+	l, err := NewLRUWithExpire(10, 50*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if k, v, ok := l.RemoveOldest(); ok {
+		t.Fatalf("bad: %v, %v, %v", k, v, ok)
+	}
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
+
+// fakeClock is a Clock that only moves when advanced
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.now = f.now.Add(d)
+}
+
+// countingClock is a Clock that counts how often it is read
+type countingClock struct {
+	reads int
+}
+
+func (c *countingClock) Now() time.Time {
+	c.reads++
+	return time.Now()
+}
+
+// Test that hits in a cache without TTLs or access tracking read no clock
+func TestLRU_NoTTLClockReads(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &countingClock{}
+	l.SetClock(clock)
+	l.Add(1, 1)
+	reads := clock.reads
+	l.Get(1)
+	l.Peek(1)
+	l.Contains(1)
+	l.Touch(1)
+	if clock.reads != reads {
+		t.Fatalf("hits should not read the clock: %v", clock.reads-reads)
+	}
+	if _, lastAccess, _ := l.PeekWithAccess(1); lastAccess.IsZero() {
+		t.Fatalf("the add time should be reported")
+	}
+
+	l.SetTrackAccess(true)
+	l.Get(1)
+	if clock.reads != reads+1 {
+		t.Fatalf("tracked hits should read the clock once: %v", clock.reads-reads)
+	}
+}
+
+// Test that expiry follows an injected clock
+func TestLRU_SetClock(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewLRUWithExpire(10, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.SetClock(clock)
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, time.Hour)
+	if _, expireAt, _ := l.GetWithExpire(1); !expireAt.Equal(clock.now.Add(time.Minute)) {
+		t.Fatalf("bad expiry: %v", expireAt)
+	}
+
+	clock.Advance(time.Minute)
+	if !l.Contains(1) {
+		t.Fatalf("1 should expire after, not at, its deadline")
+	}
+	clock.Advance(time.Second)
+	if l.Contains(1) {
+		t.Fatalf("1 should have expired")
+	}
+	if !l.Contains(2) {
+		t.Fatalf("2 should not have expired")
+	}
+
+	clock.Advance(time.Hour)
+	if l.LenValid() != 0 {
+		t.Fatalf("bad valid len: %v", l.LenValid())
+	}
+}
+
+// Test that shrinking below the length evicts with ReasonResized, growing
+// keeps the contents and non-positive sizes are rejected
+func TestLRU_ResizeBounds(t *testing.T) {
+	reasons := make(map[EvictReason]int)
+	onEvicted := func(k interface{}, v interface{}, reason EvictReason) {
+		reasons[reason]++
+	}
+	l, err := NewLRUWithReason(5, 0, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+
+	if evicted, previous := l.Resize(2); evicted != 3 || previous != 5 {
+		t.Fatalf("bad: %v, %v", evicted, previous)
+	}
+	if reasons[ReasonResized] != 3 || len(reasons) != 1 {
+		t.Fatalf("bad reasons: %v", reasons)
+	}
+	if keys := l.Keys(); len(keys) != 2 || keys[0] != 3 || keys[1] != 4 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+
+	for _, size := range []int{0, -1} {
+		if evicted, previous := l.Resize(size); evicted != 0 || previous != 2 {
+			t.Fatalf("bad: %v, %v", evicted, previous)
+		}
+		if l.Cap() != 2 || l.Len() != 2 {
+			t.Fatalf("Resize(%d) should be rejected: %v, %v", size, l.Cap(), l.Len())
+		}
+	}
+
+	if evicted, previous := l.Resize(10); evicted != 0 || previous != 2 {
+		t.Fatalf("bad: %v, %v", evicted, previous)
+	}
+	if keys := l.Keys(); len(keys) != 2 || keys[0] != 3 || keys[1] != 4 {
+		t.Fatalf("growing should not change the contents: %v", keys)
+	}
+}
+
+// Test that GetQuiet never modifies the cache
+func TestLRU_GetQuiet(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewLRUWithSlidingExpire(3, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.SetClock(clock)
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.AddEx(3, 3, time.Second)
+	clock.Advance(2 * time.Second)
+
+	if v, ok := l.GetQuiet(1); !ok || v != 1 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if _, ok := l.GetQuiet(3); ok {
+		t.Fatalf("3 should have expired")
+	}
+
+	views := l.Snapshot()
+	if len(views) != 2 || views[1].Key != 1 {
+		t.Fatalf("GetQuiet should not update recency: %v", views)
+	}
+	if !views[1].ExpireAt.Equal(time.Unix(1060, 0)) {
+		t.Fatalf("GetQuiet should not slide the expiry: %v", views[1].ExpireAt)
+	}
+	if l.Len() != 3 {
+		t.Fatalf("GetQuiet should not remove expired keys: %v", l.Len())
+	}
+}
+
+// Test that Inspect reports an entry's details without modifying the cache
+func TestLRU_Inspect(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewLRUWithSlidingExpire(3, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.SetClock(clock)
+
+	l.AddWithMeta(1, 1, "meta", 0)
+	l.AddNegative(2, time.Second)
+	clock.Advance(time.Second)
+	_, version, _ := l.GetWithVersion(1)
+
+	info, ok := l.Inspect(1)
+	if !ok || info.Value != 1 || info.Meta != "meta" || info.Version != version || info.Negative {
+		t.Fatalf("bad: %+v, %v", info, ok)
+	}
+	if !info.ExpireAt.Equal(time.Unix(1061, 0)) || !info.LastAccess.Equal(time.Unix(1001, 0)) {
+		t.Fatalf("bad times: %+v", info)
+	}
+	if info, ok := l.Inspect(2); !ok || !info.Negative {
+		t.Fatalf("2 should be negative: %+v, %v", info, ok)
+	}
+
+	clock.Advance(time.Second)
+	if _, ok := l.Inspect(2); ok {
+		t.Fatalf("2 should have expired")
+	}
+	if l.Len() != 2 {
+		t.Fatalf("Inspect should not remove expired keys: %v", l.Len())
+	}
+	if info, _ := l.Inspect(1); !info.ExpireAt.Equal(time.Unix(1061, 0)) {
+		t.Fatalf("Inspect should not slide the expiry: %v", info.ExpireAt)
+	}
+}
+
+// Test that KeysPage returns windows of the key ordering
+func TestLRU_KeysPage(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+	l.AddEx(5, 5, 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	keys, total := l.KeysPage(1, 2)
+	if total != 5 || len(keys) != 2 || keys[0] != 1 || keys[1] != 2 {
+		t.Fatalf("bad page: %v, %v", keys, total)
+	}
+	keys, total = l.KeysPage(4, 10)
+	if total != 5 || len(keys) != 1 || keys[0] != 4 {
+		t.Fatalf("bad page: %v, %v", keys, total)
+	}
+	keys, total = l.KeysPage(10, 10)
+	if total != 5 || len(keys) != 0 {
+		t.Fatalf("bad page: %v, %v", keys, total)
+	}
+	keys, total = l.KeysPage(0, 0)
+	if total != 5 || len(keys) != 0 {
+		t.Fatalf("bad page: %v, %v", keys, total)
+	}
+}
+
+// Test that the expiry index tracks adds, updates and removals so
+// RemoveExpired removes exactly the expired entries
+func TestLRU_ExpiryIndex(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewLRU(100, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.SetClock(clock)
+
+	for i := 0; i < 50; i++ {
+		l.AddEx(i, i, time.Duration(i+1)*time.Second)
+	}
+	l.Add(50, 50)
+	l.Add(10, 10)                // no longer expires
+	l.AddEx(45, 45, time.Second) // expires sooner
+	l.AddEx(5, 5, time.Hour)     // expires later
+	l.Remove(20)
+	l.RemoveOldest() // removes 0
+	if len(l.expiries) != 47 {
+		t.Fatalf("bad index size: %v", len(l.expiries))
+	}
+
+	clock.Advance(30*time.Second + time.Millisecond)
+	// 1 to 29 expire except 5, 10 and 20, and so does 45
+	if removed := l.RemoveExpired(); removed != 27 {
+		t.Fatalf("bad removed: %v", removed)
+	}
+	for i := 30; i <= 50; i++ {
+		if l.Contains(i) == (i == 45) {
+			t.Fatalf("bad contains for %d", i)
+		}
+	}
+	if !l.Contains(5) || !l.Contains(10) || l.Contains(29) {
+		t.Fatalf("bad contains")
+	}
+	if removed := l.RemoveExpired(); removed != 0 {
+		t.Fatalf("bad removed: %v", removed)
+	}
+
+	l.Purge()
+	if len(l.expiries) != 0 {
+		t.Fatalf("bad index size: %v", len(l.expiries))
+	}
+}
+
+func BenchmarkLRU_Churn(b *testing.B) {
+	l, err := NewLRU(8192, nil)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 8192; i++ {
+		l.Add(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Add(8192+i, i)
+		l.Remove(i)
+	}
+}
+
+// Test that recycled entries do not carry state over to new keys
+func TestLRU_EntryReuse(t *testing.T) {
+	l, err := NewLRU(1, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddWithMeta(1, 1, "meta", time.Minute)
+	l.Remove(1)
+	l.AddNegative(2, time.Minute)
+	l.Add(3, 3) // evicts 2
+
+	if meta, ok := l.GetMeta(3); !ok || meta != nil {
+		t.Fatalf("bad meta: %v, %v", meta, ok)
+	}
+	if isNegative, ok := l.GetNegative(3); !ok || isNegative {
+		t.Fatalf("bad negative: %v, %v", isNegative, ok)
+	}
+	if exists, remaining := l.ContainsWithTTL(3); !exists || remaining != -1 {
+		t.Fatalf("bad ttl: %v, %v", exists, remaining)
+	}
+}
+
+// Test that reads skip expiry only while no entry has a TTL
+func TestLRU_NoTTLFastPath(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.SetClock(clock)
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, time.Minute)
+	clock.Advance(2 * time.Minute)
+	if l.Contains(2) {
+		t.Fatalf("2 should have expired")
+	}
+	if !l.Contains(1) {
+		t.Fatalf("1 should not have expired")
+	}
+
+	l.AddEx(3, 3, time.Minute)
+	l.Add(3, 3)
+	clock.Advance(time.Hour)
+	if _, ok := l.Peek(3); !ok {
+		t.Fatalf("3 should have lost its expiry")
+	}
+}
+
+func BenchmarkLRU_Get(b *testing.B) {
+	l, err := NewLRU(8192, nil)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+	keys := make([]interface{}, 8192)
+	for i := range keys {
+		keys[i] = i
+		l.Add(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Get(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkLRU_GetTrackAccess(b *testing.B) {
+	l, err := NewLRU(8192, nil)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+	l.SetTrackAccess(true)
+	keys := make([]interface{}, 8192)
+	for i := range keys {
+		keys[i] = i
+		l.Add(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Get(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkLRU_GetExpire(b *testing.B) {
+	l, err := NewLRUWithExpire(8192, time.Hour, nil)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+	keys := make([]interface{}, 8192)
+	for i := range keys {
+		keys[i] = i
+		l.Add(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Get(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkLRU_Peek(b *testing.B) {
+	l, err := NewLRU(8192, nil)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+	keys := make([]interface{}, 8192)
+	for i := range keys {
+		keys[i] = i
+		l.Add(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Peek(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkLRU_PeekExpire(b *testing.B) {
+	l, err := NewLRUWithExpire(8192, time.Hour, nil)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+	keys := make([]interface{}, 8192)
+	for i := range keys {
+		keys[i] = i
+		l.Add(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Peek(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkLRU_Contains(b *testing.B) {
+	l, err := NewLRU(8192, nil)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+	keys := make([]interface{}, 8192)
+	for i := range keys {
+		keys[i] = i
+		l.Add(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Contains(keys[i%len(keys)])
+	}
+}
+
+// Test that the add callback reports inserts and updates
+func TestLRU_OnAdd(t *testing.T) {
+	l, err := NewLRU(2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var inserts, updates int
+	l.SetOnAdd(func(k interface{}, v interface{}, updated bool) {
+		if updated {
+			updates++
+		} else {
+			inserts++
+		}
+	})
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, time.Minute)
+	l.Add(1, 10)
+	l.Add(3, 3)
+	l.Update(3, 30) // Update is not an add
+	if inserts != 3 || updates != 1 {
+		t.Fatalf("bad callbacks: %v inserts, %v updates", inserts, updates)
+	}
+
+	l.SetOnAdd(nil)
+	l.Add(4, 4)
+	if inserts != 3 {
+		t.Fatalf("callback should be disabled")
+	}
+}
+
+// Test that Purge reports every entry once, oldest first, sending expired
+// entries to the expire callback
+func TestLRU_PurgeCallbacks(t *testing.T) {
+	var evicted, expired []interface{}
+	onEvicted := func(k interface{}, v interface{}) {
+		evicted = append(evicted, k)
+	}
+	onExpired := func(k interface{}, v interface{}) {
+		expired = append(expired, k)
+	}
+	l, err := NewLRUWithExpireCallback(10, 0, onEvicted, onExpired)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+	l.AddEx(5, 5, 50*time.Millisecond)
+	l.AddEx(6, 6, time.Minute)
+	l.Get(0)
+	time.Sleep(100 * time.Millisecond)
+
+	l.Purge()
+	if len(evicted) != 6 || len(expired) != 1 || expired[0] != 5 {
+		t.Fatalf("bad callbacks: %v, %v", evicted, expired)
+	}
+	for i, k := range []int{1, 2, 3, 4, 6, 0} {
+		if evicted[i] != k {
+			t.Fatalf("bad order: %v", evicted)
+		}
+	}
+	if l.Len() != 0 || len(l.items) != 0 || len(l.expiries) != 0 {
+		t.Fatalf("cache should be empty")
+	}
+
+	// The cache is usable after a purge
+	l.AddEx(1, 1, time.Minute)
+	if v, ok := l.Get(1); !ok || v != 1 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+}
+
+// Test that CompareAndSwap only swaps matching values and only then
+// updates recency
+func TestLRU_CompareAndSwap(t *testing.T) {
+	l, err := NewLRU(2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	l.SetClock(clock)
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if l.CompareAndSwap(1, 10, 11) {
+		t.Fatalf("should not swap a different value")
+	}
+	if k, _, _ := l.GetOldest(); k != 1 {
+		t.Fatalf("a failed swap should not update recency: %v", k)
+	}
+	if !l.CompareAndSwap(1, 1, 11) {
+		t.Fatalf("should swap a matching value")
+	}
+	if v, _ := l.Peek(1); v != 11 {
+		t.Fatalf("bad value: %v", v)
+	}
+	if k, _, _ := l.GetOldest(); k != 2 {
+		t.Fatalf("a swap should update recency: %v", k)
+	}
+	if l.CompareAndSwap(3, nil, 3) || l.Contains(3) {
+		t.Fatalf("should not swap a missing key")
+	}
+
+	l.AddEx(2, 2, time.Second)
+	clock.Advance(2 * time.Second)
+	if l.CompareAndSwap(2, 2, 20) {
+		t.Fatalf("should not swap an expired key")
+	}
+
+	l.Add(4, []int{1})
+	sameLen := func(a, b interface{}) bool {
+		return len(a.([]int)) == len(b.([]int))
+	}
+	if !l.CompareAndSwapFunc(4, []int{2}, []int{1, 2}, sameLen) {
+		t.Fatalf("should swap with a custom equality")
+	}
+	if l.CompareAndSwapFunc(4, []int{2}, []int{3}, sameLen) {
+		t.Fatalf("should not swap with a custom equality")
+	}
+}
+
+// Test that Increment and Decrement only modify int64 values
+func TestLRU_Increment(t *testing.T) {
+	l, err := NewLRU(2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add("n", int64(5))
+	l.Add("s", "five")
+	if n, ok := l.Increment("n", 3); !ok || n != 8 {
+		t.Fatalf("bad: %v, %v", n, ok)
+	}
+	if k, _, _ := l.GetOldest(); k != "s" {
+		t.Fatalf("increment should update recency: %v", k)
+	}
+	if n, ok := l.Decrement("n", 10); !ok || n != -2 {
+		t.Fatalf("bad: %v, %v", n, ok)
+	}
+	if v, _ := l.Peek("n"); v != int64(-2) {
+		t.Fatalf("bad value: %v", v)
+	}
+
+	if _, ok := l.Increment("s", 1); ok {
+		t.Fatalf("should not increment a non-int64 value")
+	}
+	if v, _ := l.Peek("s"); v != "five" {
+		t.Fatalf("bad value: %v", v)
+	}
+	if k, _, _ := l.GetOldest(); k != "s" {
+		t.Fatalf("a failed increment should not update recency: %v", k)
+	}
+	if _, ok := l.Increment("missing", 1); ok || l.Contains("missing") {
+		t.Fatalf("should not increment a missing key")
+	}
+}
+
+// Test that TTLStats summarizes the remaining TTL of live entries
+func TestLRU_TTLStats(t *testing.T) {
+	l, err := NewLRU(200, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	l.SetClock(clock)
+
+	if stats := l.TTLStats(); stats != (TTLStats{}) {
+		t.Fatalf("bad empty stats: %+v", stats)
+	}
+
+	for i := 2; i <= 101; i++ {
+		l.AddEx(i, i, time.Duration(i)*time.Second)
+	}
+	l.Add("forever", 0)
+	l.AddEx("soon", 0, time.Millisecond)
+	clock.Advance(time.Second)
+
+	stats := l.TTLStats()
+	if stats.Count != 100 || stats.NoExpiry != 1 {
+		t.Fatalf("bad counts: %+v", stats)
+	}
+	if stats.Min != time.Second || stats.Max != 100*time.Second || stats.Mean != 50500*time.Millisecond {
+		t.Fatalf("bad min, max or mean: %+v", stats)
+	}
+	near := func(got, want time.Duration) bool {
+		diff := got - want
+		if diff < 0 {
+			diff = -diff
+		}
+		return diff <= want/16
+	}
+	if !near(stats.P50, 50*time.Second) || !near(stats.P90, 90*time.Second) || !near(stats.P99, 99*time.Second) {
+		t.Fatalf("bad percentiles: %+v", stats)
+	}
+
+	// TTLStats does not allocate
+	if allocs := testing.AllocsPerRun(10, func() { l.TTLStats() }); allocs != 0 {
+		t.Fatalf("bad allocs: %v", allocs)
+	}
+
+	// TTLStats does not remove expired entries
+	if l.Len() != 102 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
+
+// Test that every duration maps to a bucket whose value is within 1/16 of it
+func TestLRU_TTLBuckets(t *testing.T) {
+	check := func(d time.Duration) {
+		i := ttlBucket(d)
+		if i < 0 || i >= ttlBuckets {
+			t.Fatalf("bucket %d out of range for %v", i, d)
+		}
+		diff := ttlBucketValue(i) - d
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > d/16 {
+			t.Fatalf("bucket value %v too far from %v", ttlBucketValue(i), d)
+		}
+	}
+	for d := time.Duration(0); d < 4096; d++ {
+		check(d)
+	}
+	for d := time.Duration(4096); d > 0 && d < math.MaxInt64/3; d = d*3 + 7 {
+		check(d)
+	}
+	check(math.MaxInt64)
+}
+
+// Test that Map transforms values in place and removes rejected entries
+func TestLRU_Map(t *testing.T) {
+	var evicted []interface{}
+	onEvicted := func(k interface{}, v interface{}) {
+		evicted = append(evicted, k)
+	}
+	l, err := NewLRU(10, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+	l.Get(0)
+
+	l.Map(func(k interface{}, v interface{}) (interface{}, bool) {
+		return v.(int) * 10, v.(int)%2 == 0
+	})
+	if len(evicted) != 2 || evicted[0] != 1 || evicted[1] != 3 {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+	for i, k := range l.Keys() {
+		want := []int{2, 4, 0}[i]
+		if k != want {
+			t.Fatalf("recency should be unchanged: %v", l.Keys())
+		}
+		if v, _ := l.Peek(k); v != want*10 {
+			t.Fatalf("bad value for %v: %v", k, v)
+		}
+	}
+}
+
+// Test that a watermark cache evicts in batches down to the low watermark
+func TestLRU_Watermark(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		evictCounter++
+	}
+	if _, err := NewLRUWithWatermark(10, 0, nil); err == nil {
+		t.Fatalf("should fail on a zero ratio")
+	}
+	if _, err := NewLRUWithWatermark(10, 1.5, nil); err == nil {
+		t.Fatalf("should fail on a ratio above 1")
+	}
+	l, err := NewLRUWithWatermark(10, 0.7, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if l.Add(i, i) {
+			t.Fatalf("should not have an eviction")
+		}
+	}
+	if !l.Add(10, 10) {
+		t.Fatalf("should have an eviction")
+	}
+	if evictCounter != 4 || l.Len() != 7 {
+		t.Fatalf("bad evict count or len: %v, %v", evictCounter, l.Len())
+	}
+	if l.Evictions() != 4 {
+		t.Fatalf("bad evictions: %v", l.Evictions())
+	}
+	for i, k := range l.Keys() {
+		if k != i+4 {
+			t.Fatalf("the oldest entries should be evicted: %v", l.Keys())
+		}
+	}
+
+	// No further evictions until the cache is full again
+	for i := 11; i < 14; i++ {
+		if l.Add(i, i) {
+			t.Fatalf("should not have an eviction")
+		}
+	}
+	if evictCounter != 4 || l.Len() != 10 {
+		t.Fatalf("bad evict count or len: %v, %v", evictCounter, l.Len())
+	}
+
+	// A tiny ratio still keeps the new entry
+	l, err = NewLRUWithWatermark(2, 0.1, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	if l.Len() != 1 || !l.Contains(3) {
+		t.Fatalf("only the new entry should be kept: %v", l.Keys())
+	}
+}
+
+// Test that GetAndRefresh extends the expiry by the given TTL
+func TestLRU_GetAndRefresh(t *testing.T) {
+	l, err := NewLRUWithExpire(2, time.Second, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	l.SetClock(clock)
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	clock.Advance(500 * time.Millisecond)
+	if v, ok := l.GetAndRefresh(1, time.Minute); !ok || v != 1 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if k, _, _ := l.GetOldest(); k != 2 {
+		t.Fatalf("should update recency: %v", k)
+	}
+	if _, remaining := l.ContainsWithTTL(1); remaining != time.Minute {
+		t.Fatalf("bad remaining: %v", remaining)
+	}
+
+	// A non-positive TTL falls back to the default
+	if _, ok := l.GetAndRefresh(2, 0); !ok {
+		t.Fatalf("2 should be present")
+	}
+	if _, remaining := l.ContainsWithTTL(2); remaining != time.Second {
+		t.Fatalf("bad remaining: %v", remaining)
+	}
+
+	clock.Advance(2 * time.Second)
+	if _, ok := l.GetAndRefresh(2, time.Minute); ok || l.Contains(2) {
+		t.Fatalf("2 should have expired")
+	}
+	if _, ok := l.GetAndRefresh(3, time.Minute); ok || l.Contains(3) {
+		t.Fatalf("should not insert a missing key")
+	}
+	if v, ok := l.Get(1); !ok || v != 1 {
+		t.Fatalf("1 should still be present: %v, %v", v, ok)
+	}
+}
+
+// Test that PeekRaw surfaces expired entries without removing them
+func TestLRU_PeekRaw(t *testing.T) {
+	l, err := NewLRU(2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	l.SetClock(clock)
+
+	l.AddEx(1, 1, time.Second)
+	l.Add(2, 2)
+	if v, expired, ok := l.PeekRaw(1); !ok || expired || v != 1 {
+		t.Fatalf("bad: %v, %v, %v", v, expired, ok)
+	}
+
+	clock.Advance(2 * time.Second)
+	if v, expired, ok := l.PeekRaw(1); !ok || !expired || v != 1 {
+		t.Fatalf("bad: %v, %v, %v", v, expired, ok)
+	}
+	if k, _, _ := l.PeekOldest(); k != 2 || l.Len() != 2 {
+		t.Fatalf("PeekRaw should not modify the cache")
+	}
+	if _, ok := l.Peek(1); ok {
+		t.Fatalf("other accessors should hide the expired entry")
+	}
+	if _, _, ok := l.PeekRaw(1); ok {
+		t.Fatalf("1 should have been removed")
+	}
+}
+
+// Test that a stored nil value is reported as present
+func TestLRU_NilValue(t *testing.T) {
+	l, err := NewLRU(2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, nil)
+	if v, ok := l.Get(1); !ok || v != nil {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if v, ok := l.Peek(1); !ok || v != nil {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if !l.Contains(1) {
+		t.Fatalf("1 should be present")
+	}
+	if _, ok := l.Get(2); ok {
+		t.Fatalf("2 should be missing")
+	}
+}
+
+// Test that GetDelete returns and removes an entry
+func TestLRU_GetDelete(t *testing.T) {
+	var reasons []EvictReason
+	l, err := NewLRUWithReason(10, 0, func(k interface{}, v interface{}, reason EvictReason) {
+		reasons = append(reasons, reason)
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	l.SetClock(clock)
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, time.Second)
+	if v, ok := l.GetDelete(1); !ok || v != 1 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if l.Contains(1) {
+		t.Fatalf("1 should have been removed")
+	}
+	if _, ok := l.GetDelete(1); ok {
+		t.Fatalf("1 should only be returned once")
+	}
+
+	clock.Advance(2 * time.Second)
+	if _, ok := l.GetDelete(2); ok {
+		t.Fatalf("2 should have expired")
+	}
+	if len(reasons) != 2 || reasons[0] != ReasonRemoved || reasons[1] != ReasonExpired {
+		t.Fatalf("bad reasons: %v", reasons)
+	}
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
+
+// Test that AddUntil expires entries at the given deadline
+func TestLRU_AddUntil(t *testing.T) {
+	l, err := NewLRUWithExpire(10, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	l.SetClock(clock)
+
+	deadline := clock.Now().Add(time.Second)
+	l.AddUntil(1, 1, deadline)
+	if _, expireAt, ok := l.GetWithExpire(1); !ok || !expireAt.Equal(deadline) {
+		t.Fatalf("bad expiry: %v, %v", expireAt, ok)
+	}
+
+	// A zero deadline overrides the default expiry
+	l.AddUntil(2, 2, time.Time{})
+	if _, remaining := l.ContainsWithTTL(2); remaining != -1 {
+		t.Fatalf("2 should never expire: %v", remaining)
+	}
+
+	// A past deadline is expired from the start
+	l.AddUntil(3, 3, clock.Now().Add(-time.Second))
+	if _, ok := l.Get(3); ok {
+		t.Fatalf("3 should have expired")
+	}
+
+	clock.Advance(2 * time.Second)
+	if _, ok := l.Get(1); ok {
+		t.Fatalf("1 should have expired")
+	}
+	clock.Advance(time.Hour)
+	if _, ok := l.Get(2); !ok {
+		t.Fatalf("2 should still be present")
+	}
+}
+
+// Test that RemovePrefix only removes string keys with the prefix
+func TestLRU_RemovePrefix(t *testing.T) {
+	var evicted []interface{}
+	l, err := NewLRU(10, func(k interface{}, v interface{}) {
+		evicted = append(evicted, k)
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add("user:1:name", 1)
+	l.Add("user:12:name", 2)
+	l.Add("user:1:email", 3)
+	l.Add("group:1", 4)
+	l.Add(1, 5)
+
+	if removed := l.RemovePrefix("user:1:"); removed != 2 {
+		t.Fatalf("bad removed count: %v", removed)
+	}
+	if len(evicted) != 2 || evicted[0] != "user:1:name" || evicted[1] != "user:1:email" {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+	if l.Len() != 3 || !l.Contains("user:12:name") {
+		t.Fatalf("bad keys: %v", l.Keys())
+	}
+
+	// The empty prefix matches every string key
+	if removed := l.RemovePrefix(""); removed != 2 || !l.Contains(1) {
+		t.Fatalf("bad removed count: %v", removed)
+	}
+}
+
+// Test that reads update the last access time reported by PeekWithAccess
+// when access times are tracked
+func TestLRU_PeekWithAccess(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	l.SetClock(clock)
+	l.SetTrackAccess(true)
+
+	added := clock.Now()
+	l.Add(1, 1)
+	l.Add(2, 2)
+	clock.Advance(time.Second)
+	if v, lastAccess, ok := l.PeekWithAccess(1); !ok || v != 1 || !lastAccess.Equal(added) {
+		t.Fatalf("bad: %v, %v, %v", v, lastAccess, ok)
+	}
+
+	// Peeking is not an access
+	if _, lastAccess, _ := l.PeekWithAccess(1); !lastAccess.Equal(added) {
+		t.Fatalf("peek should not update the access time: %v", lastAccess)
+	}
+
+	l.Get(1)
+	clock.Advance(time.Second)
+	l.Touch(2)
+	if _, lastAccess, _ := l.PeekWithAccess(1); !lastAccess.Equal(added.Add(time.Second)) {
+		t.Fatalf("get should update the access time: %v", lastAccess)
+	}
+	if _, lastAccess, _ := l.PeekWithAccess(2); !lastAccess.Equal(clock.Now()) {
+		t.Fatalf("touch should update the access time: %v", lastAccess)
+	}
+	if _, _, ok := l.PeekWithAccess(3); ok {
+		t.Fatalf("3 should be missing")
+	}
+}
+
+// Test that TryResize reports non-positive sizes as errors
+func TestLRU_TryResize(t *testing.T) {
+	l, err := NewLRU(5, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+
+	for _, size := range []int{0, -1} {
+		if evicted, previous, err := l.TryResize(size); err == nil || evicted != 0 || previous != 5 {
+			t.Fatalf("TryResize(%d) should fail: %v, %v, %v", size, evicted, previous, err)
+		}
+		if l.Cap() != 5 || l.Len() != 5 {
+			t.Fatalf("cache should be unchanged: %v, %v", l.Cap(), l.Len())
+		}
+	}
+	if evicted, previous, err := l.TryResize(3); err != nil || evicted != 2 || previous != 5 {
+		t.Fatalf("bad: %v, %v, %v", evicted, previous, err)
+	}
+	if l.Cap() != 3 {
+		t.Fatalf("bad cap: %v", l.Cap())
+	}
+}
+
+// Test that All and Backward iterate unexpired entries without promoting
+func TestLRU_All(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	l.SetClock(clock)
+
+	for i := 0; i < 5; i++ {
+		l.Add(i, i*10)
+	}
+	l.AddEx(5, 50, time.Second)
+	l.Get(0)
+	clock.Advance(2 * time.Second)
+
+	var keys []interface{}
+	for k, v := range l.All() {
+		if v != k.(int)*10 {
+			t.Fatalf("bad value for %v: %v", k, v)
+		}
+		keys = append(keys, k)
+	}
+	for i, k := range []int{1, 2, 3, 4, 0} {
+		if keys[i] != k {
+			t.Fatalf("bad order: %v", keys)
+		}
+	}
+
+	keys = keys[:0]
+	for k := range l.Backward() {
+		keys = append(keys, k)
+		if len(keys) == 3 {
+			break
+		}
+	}
+	for i, k := range []int{0, 4, 3} {
+		if keys[i] != k {
+			t.Fatalf("bad order: %v", keys)
+		}
+	}
+
+	if k, _, _ := l.PeekOldest(); k != 1 {
+		t.Fatalf("iteration should not update recency: %v", k)
+	}
+}
+
+// Test that versions change with every value change
+func TestLRU_Version(t *testing.T) {
+	l, err := NewLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	_, v1, ok := l.GetWithVersion(1)
+	if !ok {
+		t.Fatalf("1 should be present")
+	}
+	if !l.UpdateIfVersion(1, 2, v1) {
+		t.Fatalf("should update with the current version")
+	}
+	if l.UpdateIfVersion(1, 3, v1) {
+		t.Fatalf("should not update with a stale version")
+	}
+	value, v2, _ := l.GetWithVersion(1)
+	if value != 2 || v2 <= v1 {
+		t.Fatalf("bad: %v, %v", value, v2)
+	}
+
+	// Reading does not change the version, but every write does
+	if _, v, _ := l.GetWithVersion(1); v != v2 {
+		t.Fatalf("reading should not change the version")
+	}
+	l.Update(1, 4)
+	if _, v, _ := l.GetWithVersion(1); v <= v2 {
+		t.Fatalf("Update should change the version")
+	}
+
+	// A re-added key never reuses an old version
+	_, v3, _ := l.GetWithVersion(1)
+	l.Remove(1)
+	l.Add(1, 5)
+	if l.UpdateIfVersion(1, 6, v3) {
+		t.Fatalf("should not update a re-added key with an old version")
+	}
+	if l.UpdateIfVersion(2, 6, 0) || l.Contains(2) {
+		t.Fatalf("should not update a missing key")
+	}
+}
+
+// Test the three kinds of TTL accepted by AddEx
+func TestLRU_NoExpire(t *testing.T) {
+	l, err := NewLRUWithExpire(10, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddEx(1, 1, time.Second)
+	l.AddEx(2, 2, 0)
+	l.AddEx(3, 3, NoExpire)
+	l.AddEx(4, 4, -time.Second)
+	l.AddEx(5, 5, -1)
+	for key, want := range map[int]time.Duration{1: time.Second, 2: time.Minute, 3: -1, 4: time.Minute, 5: time.Minute} {
+		_, remaining := l.ContainsWithTTL(key)
+		if want > 0 && (remaining <= want-time.Second/2 || remaining > want) {
+			t.Fatalf("bad remaining for %d: %v", key, remaining)
+		}
+		if want < 0 && remaining != -1 {
+			t.Fatalf("%d should never expire: %v", key, remaining)
+		}
+	}
+
+	// NoExpire also clears the expiry of an existing entry
+	l.AddEx(1, 1, NoExpire)
+	if _, remaining := l.ContainsWithTTL(1); remaining != -1 {
+		t.Fatalf("1 should never expire: %v", remaining)
+	}
+	if len(l.expiries) != 3 {
+		t.Fatalf("bad expiry index size: %v", len(l.expiries))
+	}
+	if _, ok := l.GetAndRefresh(2, NoExpire); !ok {
+		t.Fatalf("2 should be present")
+	}
+	if _, remaining := l.ContainsWithTTL(2); remaining != -1 {
+		t.Fatalf("2 should never expire: %v", remaining)
+	}
+}
+
+// Test that Drain hands over live entries without firing callbacks
+func TestLRU_Drain(t *testing.T) {
+	callbacks := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		callbacks++
+	}
+	l, err := NewLRUWithExpireCallback(10, 0, onEvicted, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	l.SetClock(clock)
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, time.Second)
+	l.AddEx(3, 3, time.Minute)
+	l.Add(4, 4)
+	clock.Advance(2 * time.Second)
+
+	views := l.Drain()
+	if callbacks != 0 {
+		t.Fatalf("Drain should not fire callbacks: %v", callbacks)
+	}
+	if len(views) != 3 {
+		t.Fatalf("bad entries: %v", views)
+	}
+	for i, k := range []int{1, 3, 4} {
+		if views[i].Key != k || views[i].Value != k || views[i].Position != 2-i {
+			t.Fatalf("bad entry %d: %+v", i, views[i])
+		}
+	}
+	if !views[0].ExpireAt.IsZero() || views[1].ExpireAt.IsZero() {
+		t.Fatalf("bad expiry: %+v", views)
+	}
+	if l.Len() != 0 || len(l.expiries) != 0 || l.Contains(3) {
+		t.Fatalf("cache should be empty")
+	}
+
+	l.Add(5, 5)
+	if v, ok := l.Get(5); !ok || v != 5 {
+		t.Fatalf("cache should be usable after draining: %v, %v", v, ok)
+	}
+}
+
+// Test that the max age expires entries before their own TTL
+func TestLRU_MaxAge(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewLRUWithSlidingExpire(10, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.SetClock(clock)
+	l.SetMaxAge(5 * time.Minute)
+
+	l.AddEx(1, 1, time.Hour)
+	l.AddEx(2, 2, NoExpire)
+	l.Add(3, 3)
+	l.AddEx(4, 4, time.Second)
+	if _, expireAt, _ := l.GetWithExpire(1); !expireAt.Equal(clock.now.Add(5 * time.Minute)) {
+		t.Fatalf("bad expiry: %v", expireAt)
+	}
+
+	// Sliding keeps 3 alive, but only up to the max age
+	for i := 0; i < 4; i++ {
+		clock.Advance(50 * time.Second)
+		if _, ok := l.Get(3); !ok {
+			t.Fatalf("3 should not have expired")
+		}
+	}
+	if l.Contains(4) {
+		t.Fatalf("4 should have expired by its own TTL")
+	}
+	clock.Advance(2 * time.Minute)
+	for _, k := range []int{1, 2, 3} {
+		if l.Contains(k) {
+			t.Fatalf("%v should have expired by the max age", k)
+		}
+	}
+
+	// Replacing a value restarts its age
+	l.AddEx(5, 5, time.Hour)
+	clock.Advance(4 * time.Minute)
+	l.AddEx(5, 5, time.Hour)
+	clock.Advance(4 * time.Minute)
+	if !l.Contains(5) {
+		t.Fatalf("5 should not have expired")
+	}
+
+	// A new cap applies to existing entries
+	l.SetMaxAge(time.Minute)
+	if l.Contains(5) {
+		t.Fatalf("5 should have expired by the lowered max age")
+	}
+}
+
+// Test that incremental GC removes a bounded number of expired entries
+// every few operations
+func TestLRU_IncrementalGC(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewLRUWithIncrementalGC(100, time.Minute, 3, 2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.SetClock(clock)
+	expired := 0
+	l.SetOnEvict(func(k interface{}, v interface{}) {
+		expired++
+	})
+
+	for i := 0; i < 10; i++ {
+		l.Add(i, i)
+		clock.Advance(time.Second)
+	}
+	clock.Advance(2 * time.Minute)
+
+	// GC runs after the 12th operation, removing the 2 soonest expired
+	l.AddEx("a", "a", NoExpire)
+	if expired != 0 || l.Len() != 11 {
+		t.Fatalf("bad: %v, %v", expired, l.Len())
+	}
+	l.Remove("missing")
+	if expired != 2 || l.Len() != 9 || l.items[0] != nil || l.items[1] != nil {
+		t.Fatalf("bad: %v, %v", expired, l.Len())
+	}
+
+	for i := 0; i < 3; i++ {
+		l.Add("a", "a")
+	}
+	if expired != 4 || l.Len() != 7 {
+		t.Fatalf("bad: %v, %v", expired, l.Len())
+	}
+
+	if _, err := NewLRUWithIncrementalGC(10, time.Minute, 0, 1); !errors.Is(err, ErrInvalidGCInterval) {
+		t.Fatalf("should fail on a zero GC interval: %v", err)
+	}
+	if _, err := NewLRUWithIncrementalGC(10, time.Minute, 1, 0); !errors.Is(err, ErrInvalidGCScanLimit) {
+		t.Fatalf("should fail on a zero scan limit: %v", err)
+	}
+}
+
+// Test that SetTTL changes only the expiry of existing keys
+func TestLRU_SetTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewLRUWithExpire(10, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.SetClock(clock)
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	clock.Advance(30 * time.Second)
+	if !l.SetTTL(1, time.Hour) {
+		t.Fatalf("should have set the TTL of 1")
+	}
+	if !l.SetTTL(2, NoExpire) {
+		t.Fatalf("should have set the TTL of 2")
+	}
+	if l.SetTTL(4, time.Hour) || l.Contains(4) {
+		t.Fatalf("should not insert 4")
+	}
+	if keys := l.Keys(); keys[0] != 1 || keys[2] != 3 {
+		t.Fatalf("SetTTL should not update recency: %v", keys)
+	}
+	if _, expireAt, _ := l.GetWithExpire(1); !expireAt.Equal(clock.now.Add(time.Hour)) {
+		t.Fatalf("bad expiry: %v", expireAt)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if l.SetTTL(3, time.Hour) {
+		t.Fatalf("should not revive the expired 3")
+	}
+	if v, ok := l.Peek(1); !ok || v != 1 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if _, ok := l.Peek(2); !ok {
+		t.Fatalf("2 should never expire")
+	}
+}
+
+// Test that auto growth raises the size up to the max, then evicts
+func TestLRU_AutoGrow(t *testing.T) {
+	evictCounter := 0
+	l, err := NewLRU(4, func(k interface{}, v interface{}) {
+		evictCounter++
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := l.SetAutoGrow(2, 2); !errors.Is(err, ErrInvalidGrowMax) {
+		t.Fatalf("should fail on a max size below the size: %v", err)
+	}
+	if err := l.SetAutoGrow(10, 1); !errors.Is(err, ErrInvalidGrowFactor) {
+		t.Fatalf("should fail on a grow factor of 1: %v", err)
+	}
+	if err := l.SetAutoGrow(10, 1.5); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if l.Add(i, i) {
+			t.Fatalf("should grow rather than evict")
+		}
+	}
+	if l.Cap() != 6 || l.Len() != 5 {
+		t.Fatalf("bad cap or len: %v, %v", l.Cap(), l.Len())
+	}
+	for i := 5; i < 10; i++ {
+		if l.Add(i, i) {
+			t.Fatalf("should grow rather than evict")
+		}
+	}
+	if l.Cap() != 10 || l.Len() != 10 || evictCounter != 0 {
+		t.Fatalf("bad cap or len: %v, %v", l.Cap(), l.Len())
+	}
+
+	// At the ceiling the cache evicts as usual
+	if !l.Add(10, 10) {
+		t.Fatalf("should evict at the max size")
+	}
+	if l.Cap() != 10 || l.Len() != 10 || evictCounter != 1 || l.Contains(0) {
+		t.Fatalf("bad: %v, %v, %v", l.Cap(), l.Len(), evictCounter)
+	}
+}
+
+// Test that pinned entries are skipped by capacity eviction
+func TestLRU_Pin(t *testing.T) {
+	var evicted []interface{}
+	l, err := NewLRU(3, func(k interface{}, v interface{}) {
+		evicted = append(evicted, k)
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	if !l.Pin(1) || !l.Pin(1) || l.Pin(4) {
+		t.Fatalf("bad pin results")
+	}
+	if l.PinnedLen() != 1 {
+		t.Fatalf("bad pinned len: %v", l.PinnedLen())
+	}
+
+	// The oldest unpinned entry goes instead of 1
+	l.Add(4, 4)
+	if len(evicted) != 1 || evicted[0] != 2 || !l.Contains(1) {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+
+	// With everything else pinned the cache goes over its size
+	l.Pin(3)
+	l.Pin(4)
+	if l.Add(5, 5) || l.Len() != 4 || !l.Contains(5) {
+		t.Fatalf("should have kept every entry: %v", l.Keys())
+	}
+
+	// Unpinning lets later adds bring the cache back down
+	l.Unpin(1)
+	l.Unpin(3)
+	if l.PinnedLen() != 1 {
+		t.Fatalf("bad pinned len: %v", l.PinnedLen())
+	}
+	l.Add(6, 6)
+	if l.Len() != 3 || l.Contains(1) || l.Contains(3) || !l.Contains(5) {
+		t.Fatalf("bad keys: %v", l.Keys())
+	}
+
+	// Pinned entries can still be removed
+	if !l.Remove(4) || l.PinnedLen() != 0 {
+		t.Fatalf("bad pinned len: %v", l.PinnedLen())
+	}
+
+	if evicted, _ := l.Resize(1); evicted != 1 {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+	l.Pin(6)
+	if evicted, _ := l.Resize(1); evicted != 0 || l.Len() != 1 {
+		t.Fatalf("resize should not evict pinned entries: %v", evicted)
+	}
+
+	// RemoveOldest skips pinned entries too
+	l.Resize(3)
+	l.Add(7, 7)
+	l.Add(8, 8)
+	if k, _, ok := l.RemoveOldest(); !ok || k != 7 {
+		t.Fatalf("bad oldest: %v, %v", k, ok)
+	}
+	if removed := l.RemoveOldestN(2); removed != 1 || !l.Contains(6) || l.Len() != 1 {
+		t.Fatalf("bad removed: %v, %v", removed, l.Keys())
+	}
+	if _, _, ok := l.RemoveOldest(); ok {
+		t.Fatalf("only a pinned entry is left")
+	}
+}
+
+// Test that pinned entries still expire
+func TestLRU_PinExpire(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewLRUWithExpire(2, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.SetClock(clock)
+	l.Add(1, 1)
+	l.Pin(1)
+	clock.Advance(2 * time.Minute)
+	if l.Pin(1) || l.Contains(1) || l.PinnedLen() != 0 {
+		t.Fatalf("pinned entries should expire")
+	}
+}
+
+// Test that entries with no intervening reads are evicted strictly in
+// insertion order, one at a time, in batches and on Purge
+func TestLRU_EvictionOrder(t *testing.T) {
+	var evicted []interface{}
+	onEvicted := func(k interface{}, v interface{}) {
+		evicted = append(evicted, k)
+	}
+	l, err := NewLRU(5, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		l.Add(i, i)
+	}
+	l.Resize(2)
+	l.Purge()
+	for i, k := range evicted {
+		if k != i {
+			t.Fatalf("bad order: %v", evicted)
+		}
+	}
+	if len(evicted) != 10 {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+
+	evicted = nil
+	w, err := NewLRUWithWatermark(8, 0.5, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 9; i++ {
+		w.Add(i, i)
+	}
+	if len(evicted) != 5 {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+	for i, k := range evicted {
+		if k != i {
+			t.Fatalf("bad order: %v", evicted)
+		}
+	}
+}
+
+// Test that entries expiring at the same instant are removed in the order
+// they were written
+func TestLRU_ExpireOrderTies(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	var expired []interface{}
+	l, err := NewLRUWithExpireCallback(100, time.Minute, nil, func(k interface{}, v interface{}) {
+		expired = append(expired, k)
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.SetClock(clock)
+	for i := 0; i < 50; i++ {
+		l.Add(i, i)
+	}
+	// A new value counts as a later write
+	l.Update(0, 0)
+	clock.Advance(2 * time.Minute)
+
+	if removed := l.RemoveExpired(); removed != 50 {
+		t.Fatalf("bad removed: %v", removed)
+	}
+	for i, k := range expired[:49] {
+		if k != i+1 {
+			t.Fatalf("bad order: %v", expired)
+		}
+	}
+	if expired[49] != 0 {
+		t.Fatalf("bad order: %v", expired)
+	}
+}
+
+// Test that TTLs below the minimum are raised to it
+func TestLRU_MinTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l, err := NewLRUWithExpire(10, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.SetClock(clock)
+	l.SetMinTTL(time.Minute)
+	var clamped []time.Duration
+	l.SetOnTTLClamp(func(k interface{}, requested, ttl time.Duration) {
+		if k != 1 || ttl != time.Minute {
+			t.Fatalf("bad clamp: %v, %v", k, ttl)
+		}
+		clamped = append(clamped, requested)
+	})
+
+	expireAt := func(k interface{}) time.Time {
+		_, expireAt, _ := l.GetWithExpire(k)
+		return expireAt
+	}
+
+	// Sub-minimum TTLs are raised
+	l.AddEx(1, 1, time.Millisecond)
+	if !expireAt(1).Equal(clock.now.Add(time.Minute)) {
+		t.Fatalf("bad expiry: %v", expireAt(1))
+	}
+	if len(clamped) != 1 || clamped[0] != time.Millisecond {
+		t.Fatalf("bad clamped: %v", clamped)
+	}
+
+	// Zero falls back to the default and NoExpire never expires
+	l.AddEx(2, 2, 0)
+	if !expireAt(2).Equal(clock.now.Add(time.Hour)) {
+		t.Fatalf("bad expiry: %v", expireAt(2))
+	}
+	l.AddEx(3, 3, NoExpire)
+	if !expireAt(3).IsZero() {
+		t.Fatalf("bad expiry: %v", expireAt(3))
+	}
+
+	// Longer TTLs are left alone
+	l.AddEx(4, 4, 2*time.Minute)
+	if !expireAt(4).Equal(clock.now.Add(2 * time.Minute)) {
+		t.Fatalf("bad expiry: %v", expireAt(4))
+	}
+
+	// SetTTL is clamped too
+	l.SetTTL(1, time.Second)
+	if len(clamped) != 2 || !expireAt(1).Equal(clock.now.Add(time.Minute)) {
+		t.Fatalf("bad clamped: %v", clamped)
+	}
+
+	clock.Advance(30 * time.Second)
+	if !l.Contains(1) {
+		t.Fatalf("1 should not have expired")
+	}
+}
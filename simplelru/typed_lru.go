@@ -0,0 +1,18 @@
+package simplelru
+
+import "time"
+
+// TypedEvictCallback is used to get a callback when a TypedLRU entry is
+// evicted.
+type TypedEvictCallback[K comparable, V any] func(key K, value V)
+
+// NewTypedLRU constructs a TypedLRU of the given size
+func NewTypedLRU[K comparable, V any](size int, onEvict TypedEvictCallback[K, V]) (*TypedLRU[K, V], error) {
+	return NewTypedLRUWithExpire(size, 0, onEvict)
+}
+
+// NewTypedLRUWithExpire constructs a TypedLRU of the given size whose
+// entries expire after the given duration.
+func NewTypedLRUWithExpire[K comparable, V any](size int, expire time.Duration, onEvict TypedEvictCallback[K, V]) (*TypedLRU[K, V], error) {
+	return newTypedLRU[K, V](size, expire, onEvict, nil)
+}
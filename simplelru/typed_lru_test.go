@@ -0,0 +1,254 @@
+package simplelru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTypedLRU(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k int, v int) {
+		if k != v {
+			t.Fatalf("Evict values not equal (%v!=%v)", k, v)
+		}
+		evictCounter++
+	}
+	l, err := NewTypedLRU[int, int](128, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 256; i++ {
+		l.Add(i, i)
+	}
+	if l.Len() != 128 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+
+	if evictCounter != 128 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+
+	for i, k := range l.Keys() {
+		if v, ok := l.Get(k); !ok || v != k || v != i+128 {
+			t.Fatalf("bad key: %v", k)
+		}
+	}
+	for i := 0; i < 128; i++ {
+		if _, ok := l.Get(i); ok {
+			t.Fatalf("should be evicted")
+		}
+	}
+	for i := 128; i < 192; i++ {
+		if !l.Remove(i) {
+			t.Fatalf("should be contained")
+		}
+		if l.Remove(i) {
+			t.Fatalf("should not be contained")
+		}
+	}
+
+	l.Get(192) // expect 192 to be last key in l.Keys()
+
+	for i, k := range l.Keys() {
+		if (i < 63 && k != i+193) || (i == 63 && k != 192) {
+			t.Fatalf("out of order key: %v", k)
+		}
+	}
+
+	l.Purge()
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if _, ok := l.Get(200); ok {
+		t.Fatalf("should contain nothing")
+	}
+}
+
+func TestTypedLRU_GetOldest_RemoveOldest(t *testing.T) {
+	l, err := NewTypedLRU[string, int](2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.AddEx("a", 1, 50*time.Millisecond)
+	l.Add("b", 2)
+	l.Add("c", 3)
+
+	k, v, ok := l.GetOldest()
+	if !ok || k != "b" || v != 2 {
+		t.Fatalf("bad: %v, %v, %v", k, v, ok)
+	}
+
+	l.AddEx("d", 4, 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	k, v, ok = l.RemoveOldest()
+	if !ok || k != "c" || v != 3 {
+		t.Fatalf("bad: %v, %v, %v", k, v, ok)
+	}
+	if _, _, ok := l.RemoveOldest(); ok {
+		t.Fatalf("should contain nothing")
+	}
+}
+
+// Test that Peek and Contains don't update recent-ness
+func TestTypedLRU_PeekContains(t *testing.T) {
+	l, err := NewTypedLRU[int, string](2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, "one")
+	l.Add(2, "two")
+	if v, ok := l.Peek(1); !ok || v != "one" {
+		t.Errorf("1 should be set to one: %v, %v", v, ok)
+	}
+	if !l.Contains(1) {
+		t.Errorf("1 should be contained")
+	}
+
+	l.Add(3, "three")
+	if l.Contains(1) {
+		t.Errorf("should not have updated recent-ness of 1")
+	}
+	if v, ok := l.Peek(1); ok || v != "" {
+		t.Errorf("missing key should return the zero value: %q, %v", v, ok)
+	}
+}
+
+// Test that Resize can upsize and downsize
+func TestTypedLRU_Resize(t *testing.T) {
+	l, err := NewTypedLRU[int, int](2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if evicted, previous := l.Resize(1); evicted != 1 || previous != 2 {
+		t.Errorf("1 element should have been evicted: %v, %v", evicted, previous)
+	}
+	if evicted, previous := l.Resize(2); evicted != 0 || previous != 1 {
+		t.Errorf("0 elements should have been evicted: %v, %v", evicted, previous)
+	}
+	l.Add(3, 3)
+	if !l.Contains(2) || !l.Contains(3) {
+		t.Errorf("Cache should have contained 2 elements")
+	}
+}
+
+// Test that expire feature
+func TestTypedLRU_Expire(t *testing.T) {
+	l, err := NewTypedLRUWithExpire[int, int](10, 50*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.AddEx(2, 2, 60*time.Second)
+	time.Sleep(100 * time.Millisecond)
+	if l.Contains(1) {
+		t.Fatal("1 should not be contained")
+	}
+	if _, ok := l.Get(1); ok {
+		t.Fatal("1 should have expired")
+	}
+	if len(l.Keys()) != 1 {
+		t.Fatal("not enough keys")
+	}
+}
+
+// Test that Purge reports entries from oldest to newest
+func TestTypedLRU_PurgeOrder(t *testing.T) {
+	var evicted []int
+	l, err := NewTypedLRU[int, int](10, func(k int, v int) {
+		evicted = append(evicted, k)
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+	l.Get(0)
+	l.Purge()
+	for i, k := range []int{1, 2, 3, 4, 0} {
+		if evicted[i] != k {
+			t.Fatalf("bad order: %v", evicted)
+		}
+	}
+}
+
+// Test that TypedLRU shares the TTL handling of LRU, and that nil values of
+// an interface type are returned as nil
+func TestTypedLRU_NoExpire(t *testing.T) {
+	l, err := NewTypedLRUWithExpire[string, error](10, 50*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add("a", nil)
+	l.AddEx("b", nil, NoExpire)
+	if v, ok := l.Get("a"); !ok || v != nil {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if l.Contains("a") {
+		t.Fatalf("a should have expired")
+	}
+	if v, ok := l.Peek("b"); !ok || v != nil {
+		t.Fatalf("b should never expire: %v, %v", v, ok)
+	}
+}
+
+// Test that typed keys and values are stored without boxing, so reads and
+// overwrites do not allocate
+func TestTypedLRU_NoAllocs(t *testing.T) {
+	l, err := NewTypedLRU[int, int](128, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 128; i++ {
+		l.Add(1000+i, 1000+i)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		for i := 0; i < 128; i++ {
+			l.Add(1000+i, 2000+i)
+			l.Get(1000 + i)
+			l.Peek(1000 + i)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("bad allocs: %v", allocs)
+	}
+	if v, ok := l.Get(1000); !ok || v != 2000 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+}
+
+func BenchmarkTypedLRU_Add(b *testing.B) {
+	l, err := NewTypedLRU[int, int](8192, nil)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Add(i, i)
+	}
+}
+
+func BenchmarkLRU_Add(b *testing.B) {
+	l, err := NewLRU(8192, nil)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Add(i, i)
+	}
+}
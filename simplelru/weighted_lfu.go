@@ -0,0 +1,246 @@
+package simplelru
+
+import (
+	"math/rand"
+	"time"
+)
+
+// ScoreFunc rates how worth keeping an entry is from its access count and
+// cost. When room is needed, the lowest scoring entry of a small random
+// sample is evicted.
+type ScoreFunc func(freq uint64, cost int64) float64
+
+// DefaultScore is the ScoreFunc used by a WeightedLFU unless replaced:
+// freq / cost, with costs below 1 counted as 1. Entries that are rarely used
+// and expensive to hold are evicted first.
+func DefaultScore(freq uint64, cost int64) float64 {
+	if cost < 1 {
+		cost = 1
+	}
+	return float64(freq) / float64(cost)
+}
+
+// weightedLFUSamples is the number of entries sampled per eviction
+const weightedLFUSamples = 5
+
+// WeightedLFU implements a non-thread safe cache bounded by the total cost
+// of its entries that evicts by both popularity and cost. The cost of each
+// entry is computed by a Sizer when it is added or its value replaced, and
+// each entry counts its accesses. Rather than keeping entries ordered, an
+// eviction samples a few entries at random and removes the one with the
+// lowest score, so it costs O(1) regardless of the cache size.
+type WeightedLFU struct {
+	maxCost  int64
+	cost     int64
+	sizer    Sizer
+	score    ScoreFunc
+	onEvict  EvictCallbackWithCost
+	onReject RejectCallback
+
+	items   map[interface{}]int // key to index in entries
+	entries []*weightedLFUEntry
+	rand    *rand.Rand
+}
+
+// weightedLFUEntry is used to hold a value in the entries slice
+type weightedLFUEntry struct {
+	key   interface{}
+	value interface{}
+	cost  int64
+	freq  uint64
+}
+
+// NewWeightedLFU constructs a WeightedLFU with the given cost budget.
+func NewWeightedLFU(maxCost int64, sizer Sizer) (*WeightedLFU, error) {
+	if maxCost <= 0 {
+		return nil, ErrInvalidMaxCost
+	}
+	if sizer == nil {
+		return nil, ErrNoSizer
+	}
+	c := &WeightedLFU{
+		maxCost: maxCost,
+		sizer:   sizer,
+		score:   DefaultScore,
+		items:   make(map[interface{}]int),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	return c, nil
+}
+
+// NewWeightedLFUWithCostCallback constructs a WeightedLFU with the given
+// cost budget, reporting every removal to onEvict along with the cost that
+// was reclaimed.
+func NewWeightedLFUWithCostCallback(maxCost int64, sizer Sizer, onEvict EvictCallbackWithCost) (*WeightedLFU, error) {
+	c, err := NewWeightedLFU(maxCost, sizer)
+	if err != nil {
+		return nil, err
+	}
+	c.onEvict = onEvict
+	return c, nil
+}
+
+// SetScoreFunc replaces the function used to choose eviction victims. A nil
+// function restores DefaultScore.
+func (c *WeightedLFU) SetScoreFunc(score ScoreFunc) {
+	if score == nil {
+		score = DefaultScore
+	}
+	c.score = score
+}
+
+// Add adds a value to the cache, evicting low scoring entries until the
+// total cost fits the budget. Adding counts as an access. An entry whose
+// cost alone exceeds the budget is rejected with an error, and any existing
+// value for the key is left in place. Returns true if an eviction occurred.
+func (c *WeightedLFU) Add(key, value interface{}) (evicted bool, err error) {
+	cost := c.sizer(key, value)
+	if cost < 0 {
+		return c.reject(key, value, RejectInvalidCost, ErrNegativeCost)
+	}
+	if cost > c.maxCost {
+		return c.reject(key, value, RejectOversized, ErrCostTooLarge)
+	}
+
+	if i, ok := c.items[key]; ok {
+		ent := c.entries[i]
+		c.cost += cost - ent.cost
+		ent.value = value
+		ent.cost = cost
+		ent.freq++
+	} else {
+		c.items[key] = len(c.entries)
+		c.entries = append(c.entries, &weightedLFUEntry{key: key, value: value, cost: cost, freq: 1})
+		c.cost += cost
+	}
+
+	// The entry just added is never its own victim
+	for c.cost > c.maxCost {
+		c.removeIndex(c.victim(c.items[key]))
+		evicted = true
+	}
+	return evicted, nil
+}
+
+// reject reports a refused add to the reject callback and returns err.
+func (c *WeightedLFU) reject(key, value interface{}, reason RejectReason, err error) (bool, error) {
+	if c.onReject != nil {
+		c.onReject(key, value, reason)
+	}
+	return false, err
+}
+
+// SetOnReject registers a callback invoked whenever Add refuses an entry,
+// along with the reason. A nil callback disables it.
+func (c *WeightedLFU) SetOnReject(onReject RejectCallback) {
+	c.onReject = onReject
+}
+
+// Get looks up a key's value from the cache, incrementing its access count.
+func (c *WeightedLFU) Get(key interface{}) (value interface{}, ok bool) {
+	if i, ok := c.items[key]; ok {
+		ent := c.entries[i]
+		ent.freq++
+		return ent.value, true
+	}
+	return nil, false
+}
+
+// Contains checks if a key is in the cache, without counting an access.
+func (c *WeightedLFU) Contains(key interface{}) bool {
+	_, ok := c.items[key]
+	return ok
+}
+
+// Peek returns the key value (or undefined if not found) without counting
+// an access.
+func (c *WeightedLFU) Peek(key interface{}) (value interface{}, ok bool) {
+	if i, ok := c.items[key]; ok {
+		return c.entries[i].value, true
+	}
+	return nil, false
+}
+
+// Remove removes the provided key from the cache, returning if the
+// key was contained.
+func (c *WeightedLFU) Remove(key interface{}) (present bool) {
+	if i, ok := c.items[key]; ok {
+		c.removeIndex(i)
+		return true
+	}
+	return false
+}
+
+// Keys returns a slice of the keys in the cache, in no particular order.
+func (c *WeightedLFU) Keys() []interface{} {
+	keys := make([]interface{}, len(c.entries))
+	for i, ent := range c.entries {
+		keys[i] = ent.key
+	}
+	return keys
+}
+
+// Len returns the number of items in the cache.
+func (c *WeightedLFU) Len() int {
+	return len(c.entries)
+}
+
+// Cost returns the total cost of the items in the cache.
+func (c *WeightedLFU) Cost() int64 {
+	return c.cost
+}
+
+// MaxCost returns the cost budget of the cache.
+func (c *WeightedLFU) MaxCost() int64 {
+	return c.maxCost
+}
+
+// Purge is used to completely clear the cache.
+func (c *WeightedLFU) Purge() {
+	entries := c.entries
+	c.items = make(map[interface{}]int)
+	c.entries = nil
+	c.cost = 0
+	if c.onEvict != nil {
+		for _, ent := range entries {
+			c.onEvict(ent.key, ent.value, ent.cost)
+		}
+	}
+}
+
+// victim samples a few entries other than the one at index keep and
+// returns the index of the lowest scoring one. There must be at least one
+// other entry.
+func (c *WeightedLFU) victim(keep int) int {
+	best := -1
+	var bestScore float64
+	for i := 0; i < weightedLFUSamples; i++ {
+		j := c.rand.Intn(len(c.entries))
+		if j == keep {
+			j = (j + 1) % len(c.entries)
+		}
+		ent := c.entries[j]
+		if score := c.score(ent.freq, ent.cost); best < 0 || score < bestScore {
+			best, bestScore = j, score
+		}
+	}
+	return best
+}
+
+// removeIndex removes the entry at index i by moving the last entry into
+// its place, and reports it to the eviction callback.
+func (c *WeightedLFU) removeIndex(i int) {
+	ent := c.entries[i]
+	last := len(c.entries) - 1
+	c.cost -= ent.cost
+	delete(c.items, ent.key)
+	if i != last {
+		c.entries[i] = c.entries[last]
+		c.items[c.entries[i].key] = i
+	}
+	c.entries[last] = nil
+	c.entries = c.entries[:last]
+	if c.onEvict != nil {
+		c.onEvict(ent.key, ent.value, ent.cost)
+	}
+}
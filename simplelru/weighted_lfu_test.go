@@ -0,0 +1,169 @@
+package simplelru
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestWeightedLFU(t *testing.T) {
+	l, err := NewWeightedLFU(10, stringSizer)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if evicted, err := l.Add("a", "12345"); err != nil || evicted {
+		t.Fatalf("should not have an eviction: %v", err)
+	}
+	if evicted, err := l.Add("b", "123"); err != nil || evicted {
+		t.Fatalf("should not have an eviction: %v", err)
+	}
+	if l.Cost() != 10 || l.Len() != 2 {
+		t.Fatalf("bad cost or len: %v, %v", l.Cost(), l.Len())
+	}
+
+	// Replacing a value recomputes its cost, and never evicts the entry
+	// being added
+	evicted, err := l.Add("b", "12345")
+	if err != nil || !evicted {
+		t.Fatalf("should have an eviction: %v", err)
+	}
+	if l.Contains("a") || l.Cost() != 6 {
+		t.Fatalf("a should have been evicted: %v, %v", l.Keys(), l.Cost())
+	}
+	if v, ok := l.Get("b"); !ok || v != "12345" {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+
+	if _, err := l.Add("c", "12345678910"); err == nil {
+		t.Fatalf("should reject an oversized entry")
+	}
+	if l.Len() != 1 || l.Cost() != 6 {
+		t.Fatalf("cache should be unchanged: %v", l.Keys())
+	}
+
+	l.Remove("b")
+	if l.Len() != 0 || l.Cost() != 0 {
+		t.Fatalf("bad len or cost: %v, %v", l.Len(), l.Cost())
+	}
+}
+
+// Test that frequently used entries survive a stream of one-off entries
+func TestWeightedLFU_Score(t *testing.T) {
+	l, err := NewWeightedLFU(40, func(k interface{}, v interface{}) int64 { return 1 })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.rand = rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		l.Add(i, i)
+		for j := 0; j < 10; j++ {
+			l.Get(i)
+		}
+	}
+	for i := 100; i < 300; i++ {
+		l.Add(i, i)
+	}
+
+	hot := 0
+	for i := 0; i < 20; i++ {
+		if l.Contains(i) {
+			hot++
+		}
+	}
+	if hot < 15 || l.Len() != 40 {
+		t.Fatalf("frequently used entries should be kept: %v of 20, len %v", hot, l.Len())
+	}
+}
+
+// Test that the score function can be replaced
+func TestWeightedLFU_SetScoreFunc(t *testing.T) {
+	l, err := NewWeightedLFU(10, stringSizer)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.rand = rand.New(rand.NewSource(1))
+
+	// Prefer evicting cheap entries, regardless of use
+	calls := 0
+	l.SetScoreFunc(func(freq uint64, cost int64) float64 {
+		calls++
+		return float64(cost)
+	})
+	l.Add("a", "12")
+	l.Add("b", "1234")
+	l.Get("a")
+	l.Add("c", "12")
+	if calls == 0 || l.Contains("a") || !l.Contains("b") {
+		t.Fatalf("the cheap entry should have been evicted: %v", l.Keys())
+	}
+
+	l.SetScoreFunc(nil)
+	if l.score == nil {
+		t.Fatalf("nil should restore the default score")
+	}
+}
+
+func TestNewWeightedLFU_Invalid(t *testing.T) {
+	if _, err := NewWeightedLFU(0, stringSizer); err == nil {
+		t.Fatalf("should fail on a zero budget")
+	}
+	if _, err := NewWeightedLFU(10, nil); err == nil {
+		t.Fatalf("should fail without a sizer")
+	}
+}
+
+func TestWeightedLFU_OnReject(t *testing.T) {
+	l, err := NewWeightedLFU(10, stringSizer)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var rejected []interface{}
+	l.SetOnReject(func(k interface{}, v interface{}, reason RejectReason) {
+		if reason != RejectOversized {
+			t.Fatalf("bad reason: %v", reason)
+		}
+		rejected = append(rejected, k)
+	})
+	l.Add("a", "12345")
+	l.Add("b", "12345678901")
+	if len(rejected) != 1 || rejected[0] != "b" || l.Contains("b") {
+		t.Fatalf("bad rejected: %v", rejected)
+	}
+}
+
+func TestWeightedLFU_CostCallback(t *testing.T) {
+	var reclaimed int64
+	var evicted []interface{}
+	onEvicted := func(k interface{}, v interface{}, cost int64) {
+		if cost != stringSizer(k, v) {
+			t.Fatalf("bad cost for %v: %v", k, cost)
+		}
+		reclaimed += cost
+		evicted = append(evicted, k)
+	}
+	l, err := NewWeightedLFUWithCostCallback(10, stringSizer, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add("a", "aaa")
+	l.Add("b", "bb")
+	l.Add("c", "cccc")
+	if len(evicted) != 1 || evicted[0] == "c" || reclaimed+l.Cost() != 12 {
+		t.Fatalf("bad evicted or cost: %v, %v, %v", evicted, reclaimed, l.Cost())
+	}
+	l.Remove("c")
+	if len(evicted) != 2 || evicted[1] != "c" || reclaimed+l.Cost() != 12 {
+		t.Fatalf("bad evicted or cost: %v, %v, %v", evicted, reclaimed, l.Cost())
+	}
+	l.Purge()
+	if len(evicted) != 3 || reclaimed != 12 || l.Cost() != 0 {
+		t.Fatalf("bad evicted or cost: %v, %v, %v", evicted, reclaimed, l.Cost())
+	}
+
+	if _, err := NewWeightedLFUWithCostCallback(0, stringSizer, onEvicted); err == nil {
+		t.Fatalf("should fail on a zero max cost")
+	}
+}
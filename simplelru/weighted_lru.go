@@ -0,0 +1,198 @@
+package simplelru
+
+import "math"
+
+// WeightedLRU implements a non-thread safe LRU cache bounded by the total
+// cost of its entries rather than their count. When the total cost exceeds
+// the budget, the oldest entries are evicted until it fits again.
+type WeightedLRU struct {
+	lru          *LRU
+	maxCost      int64
+	maxEntryCost int64
+	cost         int64
+	onEvict      EvictCallback
+	onEvictCost  EvictCallbackWithCost
+	onReject     RejectCallback
+}
+
+// EvictCallbackWithCost is used to get a callback when an entry is removed
+// from a weighted cache, along with the cost it gave back to the budget
+type EvictCallbackWithCost func(key interface{}, value interface{}, cost int64)
+
+// weightedItem is used to hold a value and its cost in the underlying LRU
+type weightedItem struct {
+	value interface{}
+	cost  int64
+}
+
+// NewWeightedLRU constructs a WeightedLRU with the given cost budget
+func NewWeightedLRU(maxCost int64, onEvict EvictCallback) (*WeightedLRU, error) {
+	return NewWeightedLRUWithMaxEntryCost(maxCost, 0, onEvict)
+}
+
+// NewWeightedLRUWithCostCallback constructs a WeightedLRU with the given
+// cost budget, reporting every removal to onEvict along with the cost that
+// was reclaimed.
+func NewWeightedLRUWithCostCallback(maxCost int64, onEvict EvictCallbackWithCost) (*WeightedLRU, error) {
+	c, err := NewWeightedLRU(maxCost, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.onEvictCost = onEvict
+	return c, nil
+}
+
+// NewWeightedLRUWithMaxEntryCost constructs a WeightedLRU with the given cost
+// budget that rejects any single entry costing more than maxEntryCost,
+// rather than evicting most of the cache to make room for it. A zero
+// maxEntryCost only limits entries to the whole budget.
+func NewWeightedLRUWithMaxEntryCost(maxCost, maxEntryCost int64, onEvict EvictCallback) (*WeightedLRU, error) {
+	if maxCost <= 0 {
+		return nil, ErrInvalidMaxCost
+	}
+	if maxEntryCost < 0 || maxEntryCost > maxCost {
+		return nil, ErrInvalidMaxEntryCost
+	}
+	c := &WeightedLRU{
+		maxCost:      maxCost,
+		maxEntryCost: maxEntryCost,
+		onEvict:      onEvict,
+	}
+	lru, err := NewLRU(math.MaxInt, c.evicted)
+	if err != nil {
+		return nil, err
+	}
+	c.lru = lru
+	return c, nil
+}
+
+// evicted keeps the total cost in sync as the underlying LRU drops entries.
+func (c *WeightedLRU) evicted(key, value interface{}) {
+	item := value.(*weightedItem)
+	c.cost -= item.cost
+	if c.onEvict != nil {
+		c.onEvict(key, item.value)
+	}
+	if c.onEvictCost != nil {
+		c.onEvictCost(key, item.value, item.cost)
+	}
+}
+
+// AddWeighted adds a value with the given cost to the cache, evicting the
+// oldest entries until the total cost fits the budget. An entry whose cost
+// alone exceeds the budget, or the max entry cost if one is set, is rejected
+// with an error, and any existing value for the key is left in place.
+// Returns true if an eviction occurred.
+func (c *WeightedLRU) AddWeighted(key, value interface{}, cost int64) (evicted bool, err error) {
+	if cost < 0 {
+		return c.reject(key, value, RejectInvalidCost, ErrNegativeCost)
+	}
+	if cost > c.maxCost {
+		return c.reject(key, value, RejectOversized, ErrCostTooLarge)
+	}
+	if c.maxEntryCost > 0 && cost > c.maxEntryCost {
+		return c.reject(key, value, RejectOversized, ErrEntryCostTooLarge)
+	}
+
+	if ent, ok := c.lru.items[key]; ok {
+		c.cost -= ent.value.(*weightedItem).cost
+	}
+	c.lru.Add(key, &weightedItem{value: value, cost: cost})
+	c.cost += cost
+
+	for c.cost > c.maxCost {
+		c.lru.removeOldest(ReasonCapacity)
+		evicted = true
+	}
+	return evicted, nil
+}
+
+// reject reports a refused add to the reject callback and returns err.
+func (c *WeightedLRU) reject(key, value interface{}, reason RejectReason, err error) (bool, error) {
+	if c.onReject != nil {
+		c.onReject(key, value, reason)
+	}
+	return false, err
+}
+
+// SetOnReject registers a callback invoked whenever AddWeighted refuses an
+// entry, along with the reason. A nil callback disables it.
+func (c *WeightedLRU) SetOnReject(onReject RejectCallback) {
+	c.onReject = onReject
+}
+
+// Get looks up a key's value from the cache.
+func (c *WeightedLRU) Get(key interface{}) (value interface{}, ok bool) {
+	if v, ok := c.lru.Get(key); ok {
+		return v.(*weightedItem).value, true
+	}
+	return nil, false
+}
+
+// Contains checks if a key is in the cache, without updating the
+// recent-ness.
+func (c *WeightedLRU) Contains(key interface{}) bool {
+	return c.lru.Contains(key)
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the "recently used"-ness of the key.
+func (c *WeightedLRU) Peek(key interface{}) (value interface{}, ok bool) {
+	if v, ok := c.lru.Peek(key); ok {
+		return v.(*weightedItem).value, true
+	}
+	return nil, false
+}
+
+// Remove removes the provided key from the cache, returning if the
+// key was contained.
+func (c *WeightedLRU) Remove(key interface{}) (present bool) {
+	return c.lru.Remove(key)
+}
+
+// RemoveOldest removes the oldest item from the cache.
+func (c *WeightedLRU) RemoveOldest() (key interface{}, value interface{}, ok bool) {
+	if key, value, ok = c.lru.RemoveOldest(); ok {
+		return key, value.(*weightedItem).value, true
+	}
+	return nil, nil, false
+}
+
+// GetOldest returns the oldest entry
+func (c *WeightedLRU) GetOldest() (key interface{}, value interface{}, ok bool) {
+	if key, value, ok = c.lru.GetOldest(); ok {
+		return key, value.(*weightedItem).value, true
+	}
+	return nil, nil, false
+}
+
+// Keys returns a slice of the keys in the cache, from oldest to newest.
+func (c *WeightedLRU) Keys() []interface{} {
+	return c.lru.Keys()
+}
+
+// Len returns the number of items in the cache.
+func (c *WeightedLRU) Len() int {
+	return c.lru.Len()
+}
+
+// Cost returns the total cost of the items in the cache.
+func (c *WeightedLRU) Cost() int64 {
+	return c.cost
+}
+
+// MaxCost returns the cost budget of the cache.
+func (c *WeightedLRU) MaxCost() int64 {
+	return c.maxCost
+}
+
+// MaxEntryCost returns the largest cost accepted for a single entry, or zero
+// if entries are only limited by the whole budget.
+func (c *WeightedLRU) MaxEntryCost() int64 {
+	return c.maxEntryCost
+}
+
+// Purge is used to completely clear the cache.
+func (c *WeightedLRU) Purge() {
+	c.lru.Purge()
+}
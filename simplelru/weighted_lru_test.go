@@ -0,0 +1,215 @@
+package simplelru
+
+import "testing"
+
+func TestWeightedLRU(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		if k != v {
+			t.Fatalf("Evict values not equal (%v!=%v)", k, v)
+		}
+		evictCounter++
+	}
+	l, err := NewWeightedLRU(10, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if evicted, err := l.AddWeighted(i, i, 2); err != nil || evicted {
+			t.Fatalf("should not have an eviction: %v", err)
+		}
+	}
+	if l.Cost() != 10 || l.Len() != 5 {
+		t.Fatalf("bad cost or len: %v, %v", l.Cost(), l.Len())
+	}
+
+	// A heavy entry evicts several of the oldest
+	evicted, err := l.AddWeighted(5, 5, 5)
+	if err != nil || !evicted {
+		t.Fatalf("should have an eviction: %v", err)
+	}
+	if evictCounter != 3 || l.Cost() != 9 {
+		t.Fatalf("bad evict count or cost: %v, %v", evictCounter, l.Cost())
+	}
+	for i := 0; i < 3; i++ {
+		if l.Contains(i) {
+			t.Fatalf("%d should have been evicted", i)
+		}
+	}
+	if v, ok := l.Get(5); !ok || v != 5 {
+		t.Fatalf("5 should be set to 5: %v, %v", v, ok)
+	}
+
+	// Updating a key replaces its cost
+	if _, err := l.AddWeighted(5, 5, 1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if l.Cost() != 5 {
+		t.Fatalf("bad cost: %v", l.Cost())
+	}
+
+	l.Remove(3)
+	if l.Cost() != 3 {
+		t.Fatalf("bad cost: %v", l.Cost())
+	}
+
+	l.Purge()
+	if l.Cost() != 0 || l.Len() != 0 {
+		t.Fatalf("bad cost or len: %v, %v", l.Cost(), l.Len())
+	}
+}
+
+// Test that entries costing more than the whole budget are rejected
+func TestWeightedLRU_Oversized(t *testing.T) {
+	l, err := NewWeightedLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddWeighted(1, 1, 4)
+	if _, err := l.AddWeighted(2, 2, 11); err == nil {
+		t.Fatalf("should have rejected an oversized entry")
+	}
+	if _, err := l.AddWeighted(1, 10, 11); err == nil {
+		t.Fatalf("should have rejected an oversized update")
+	}
+	if _, err := l.AddWeighted(3, 3, -1); err == nil {
+		t.Fatalf("should have rejected a negative cost")
+	}
+	if v, ok := l.Peek(1); !ok || v != 1 || l.Cost() != 4 {
+		t.Fatalf("rejected adds should not change the cache: %v, %v", v, l.Cost())
+	}
+
+	if _, err := l.AddWeighted(2, 2, 10); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if l.Len() != 1 || !l.Contains(2) {
+		t.Fatalf("an entry costing the whole budget should evict everything else")
+	}
+
+	if _, err := NewWeightedLRU(0, nil); err == nil {
+		t.Fatalf("should have rejected a zero budget")
+	}
+}
+
+func TestWeightedLRU_GetOldest_RemoveOldest(t *testing.T) {
+	l, err := NewWeightedLRU(10, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.AddWeighted(1, 1, 3)
+	l.AddWeighted(2, 2, 3)
+
+	if k, v, ok := l.GetOldest(); !ok || k != 1 || v != 1 {
+		t.Fatalf("bad: %v, %v", k, v)
+	}
+	if k, v, ok := l.RemoveOldest(); !ok || k != 1 || v != 1 {
+		t.Fatalf("bad: %v, %v", k, v)
+	}
+	if l.Cost() != 3 {
+		t.Fatalf("bad cost: %v", l.Cost())
+	}
+	if keys := l.Keys(); len(keys) != 1 || keys[0] != 2 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+}
+
+// Test that entries above the max entry cost are rejected without evicting
+func TestWeightedLRU_MaxEntryCost(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k interface{}, v interface{}) {
+		evictCounter++
+	}
+	l, err := NewWeightedLRUWithMaxEntryCost(10, 4, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		l.AddWeighted(i, i, 3)
+	}
+
+	// cost > max entry cost is rejected, leaving the cache untouched
+	if evicted, err := l.AddWeighted(3, 3, 5); err == nil || evicted {
+		t.Fatalf("should have rejected an entry above the max entry cost")
+	}
+	if evictCounter != 0 || l.Len() != 3 || l.Cost() != 9 {
+		t.Fatalf("rejection should not evict: %v, %v", evictCounter, l.Cost())
+	}
+
+	// cost == max entry cost is accepted and may evict
+	if evicted, err := l.AddWeighted(4, 4, 4); err != nil || !evicted {
+		t.Fatalf("should have added with an eviction: %v", err)
+	}
+	if evictCounter != 1 || l.Cost() != 10 {
+		t.Fatalf("bad evict count or cost: %v, %v", evictCounter, l.Cost())
+	}
+
+	// cost == 0 is always accepted
+	if evicted, err := l.AddWeighted(5, 5, 0); err != nil || evicted {
+		t.Fatalf("should have added a free entry: %v", err)
+	}
+	if l.Len() != 4 || l.Cost() != 10 {
+		t.Fatalf("bad len or cost: %v, %v", l.Len(), l.Cost())
+	}
+
+	if _, err := NewWeightedLRUWithMaxEntryCost(10, 11, nil); err == nil {
+		t.Fatalf("should have rejected a max entry cost above the max cost")
+	}
+}
+
+func TestWeightedLRU_CostCallback(t *testing.T) {
+	var reclaimed int64
+	onEvicted := func(k interface{}, v interface{}, cost int64) {
+		if k != v {
+			t.Fatalf("bad: %v, %v", k, v)
+		}
+		reclaimed += cost
+	}
+	l, err := NewWeightedLRUWithCostCallback(10, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.AddWeighted(1, 1, 4)
+	l.AddWeighted(2, 2, 3)
+	l.AddWeighted(3, 3, 5)
+	if reclaimed != 4 || l.Cost() != 8 {
+		t.Fatalf("bad reclaimed or cost: %v, %v", reclaimed, l.Cost())
+	}
+	l.Remove(3)
+	if reclaimed != 9 || l.Cost() != 3 {
+		t.Fatalf("bad reclaimed or cost: %v, %v", reclaimed, l.Cost())
+	}
+	l.Purge()
+	if reclaimed != 12 || l.Cost() != 0 {
+		t.Fatalf("bad reclaimed or cost: %v, %v", reclaimed, l.Cost())
+	}
+
+	if _, err := NewWeightedLRUWithCostCallback(0, onEvicted); err == nil {
+		t.Fatalf("should fail on a zero max cost")
+	}
+}
+
+func TestWeightedLRU_OnReject(t *testing.T) {
+	l, err := NewWeightedLRUWithMaxEntryCost(10, 5, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var reasons []RejectReason
+	l.SetOnReject(func(k interface{}, v interface{}, reason RejectReason) {
+		reasons = append(reasons, reason)
+	})
+
+	l.AddWeighted(1, 1, 5)
+	l.AddWeighted(2, 2, -1)
+	l.AddWeighted(3, 3, 6)
+	l.AddWeighted(4, 4, 11)
+	if len(reasons) != 3 || reasons[0] != RejectInvalidCost || reasons[1] != RejectOversized || reasons[2] != RejectOversized {
+		t.Fatalf("bad reasons: %v", reasons)
+	}
+	if reasons[1].String() != "oversized" {
+		t.Fatalf("bad name: %v", reasons[1])
+	}
+}
@@ -0,0 +1,138 @@
+package lru
+
+import (
+	"sync"
+
+	"github.com/iocn-io/golang-lru/simplelru"
+)
+
+// SLRUCache is a thread-safe fixed size segmented LRU cache. New entries
+// enter a probationary segment and are promoted to a protected segment when
+// they are hit again. When the protected segment is full, its least recently
+// used entry is demoted back to probation rather than evicted, so only
+// entries that fall out of probation leave the cache. This gives better scan
+// resistance than a plain LRU while being simpler than the ARCCache.
+type SLRUCache struct {
+	probation *simplelru.LRU
+	protected *simplelru.LRU
+	lock      sync.RWMutex
+}
+
+// NewSLRU creates an SLRUCache with the given segment sizes.
+func NewSLRU(probationSize, protectedSize int) (*SLRUCache, error) {
+	if probationSize <= 0 {
+		return nil, ErrInvalidSize
+	}
+	if protectedSize <= 0 {
+		return nil, ErrInvalidSize
+	}
+	probation, err := simplelru.NewLRU(probationSize, nil)
+	if err != nil {
+		return nil, err
+	}
+	protected, err := simplelru.NewLRU(protectedSize, nil)
+	if err != nil {
+		return nil, err
+	}
+	c := &SLRUCache{
+		probation: probation,
+		protected: protected,
+	}
+	return c, nil
+}
+
+// Get looks up a key's value from the cache, promoting it to the protected
+// segment if it was on probation.
+func (c *SLRUCache) Get(key interface{}) (value interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if val, ok := c.protected.Get(key); ok {
+		return val, ok
+	}
+
+	// A second hit promotes the value, demoting the protected segment's
+	// oldest entry back to probation to make room
+	if val, ok := c.probation.Peek(key); ok {
+		c.probation.Remove(key)
+		if c.protected.Len() >= c.protected.Cap() {
+			if k, v, ok := c.protected.RemoveOldest(); ok {
+				c.probation.Add(k, v)
+			}
+		}
+		c.protected.Add(key, val)
+		return val, ok
+	}
+
+	// No hit
+	return nil, false
+}
+
+// Add adds a value to the cache. New keys enter the probationary segment.
+// Returns true if an eviction occurred.
+func (c *SLRUCache) Add(key, value interface{}) (evicted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Updating a value does not count as a hit, so keys stay in their
+	// current segment
+	if c.protected.Contains(key) {
+		c.protected.Add(key, value)
+		return false
+	}
+	return c.probation.Add(key, value)
+}
+
+// Contains is used to check if the cache contains a key
+// without updating recency or promoting it.
+func (c *SLRUCache) Contains(key interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.protected.Contains(key) || c.probation.Contains(key)
+}
+
+// Peek is used to inspect the cache value of a key
+// without updating recency or promoting it.
+func (c *SLRUCache) Peek(key interface{}) (value interface{}, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if val, ok := c.protected.Peek(key); ok {
+		return val, ok
+	}
+	return c.probation.Peek(key)
+}
+
+// Remove removes the provided key from the cache.
+func (c *SLRUCache) Remove(key interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.protected.Remove(key) {
+		return
+	}
+	c.probation.Remove(key)
+}
+
+// Keys returns a slice of the keys in the cache.
+// The protected keys are first in the returned slice.
+func (c *SLRUCache) Keys() []interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	k1 := c.protected.Keys()
+	k2 := c.probation.Keys()
+	return append(k1, k2...)
+}
+
+// Len returns the number of items in the cache.
+func (c *SLRUCache) Len() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.protected.Len() + c.probation.Len()
+}
+
+// Purge is used to completely clear the cache.
+func (c *SLRUCache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.protected.Purge()
+	c.probation.Purge()
+}
@@ -0,0 +1,66 @@
+package lru
+
+import (
+	"testing"
+)
+
+func TestSLRU_InvalidParams(t *testing.T) {
+	if _, err := NewSLRU(0, 1); err == nil {
+		t.Fatalf("should fail on a zero probation size")
+	}
+	if _, err := NewSLRU(1, 0); err == nil {
+		t.Fatalf("should fail on a zero protected size")
+	}
+}
+
+func TestSLRU(t *testing.T) {
+	l, err := NewSLRU(2, 2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// New entries enter probation and are evicted from there
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if l.protected.Len() != 0 || l.probation.Len() != 2 {
+		t.Fatalf("bad segments: %v, %v", l.protected.Len(), l.probation.Len())
+	}
+
+	// A hit promotes to protected
+	if v, ok := l.Get(1); !ok || v != 1 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if !l.protected.Contains(1) {
+		t.Fatalf("1 should be protected")
+	}
+
+	// A scan only churns the probationary segment
+	for i := 10; i < 20; i++ {
+		l.Add(i, i)
+	}
+	if !l.Contains(1) {
+		t.Fatalf("1 should survive the scan")
+	}
+	if l.Len() != 3 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+
+	// Promoting into a full protected segment demotes its oldest entry
+	l.Get(18)
+	l.Get(19)
+	if l.protected.Contains(1) || !l.probation.Contains(1) {
+		t.Fatalf("1 should have been demoted to probation")
+	}
+	if keys := l.Keys(); len(keys) != 3 || keys[0] != 18 || keys[1] != 19 || keys[2] != 1 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+
+	l.Remove(18)
+	if l.Contains(18) {
+		t.Fatalf("18 should have been removed")
+	}
+	l.Purge()
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
@@ -0,0 +1,187 @@
+package lru
+
+import (
+	"sync"
+
+	"github.com/iocn-io/golang-lru/simplelru"
+)
+
+// Overflow is a secondary, usually larger and slower, store for the entries
+// a TieredCache evicts from memory, such as one backed by disk. It must be
+// safe for concurrent use, since Get is called without the cache locked.
+type Overflow interface {
+	Put(key, value interface{})
+	Get(key interface{}) (value interface{}, ok bool)
+}
+
+// OverflowRemover can optionally be implemented by an Overflow so that
+// TieredCache.Remove also deletes the key from it, and so that keys added
+// or moved back into memory do not leave a copy behind that could later be
+// returned in place of a newer value.
+type OverflowRemover interface {
+	Remove(key interface{})
+}
+
+// OverflowPurger can optionally be implemented by an Overflow so that
+// TieredCache.Purge also clears it.
+type OverflowPurger interface {
+	Purge()
+}
+
+// TieredCache is a thread-safe two-level cache. Its in-memory tier is a
+// fixed size LRU; entries evicted from it for capacity are passed to an
+// Overflow instead of being dropped, and a miss in memory falls back to the
+// overflow, moving the entry found there back into memory.
+type TieredCache struct {
+	lru      *simplelru.LRU
+	overflow Overflow
+	lock     sync.Mutex
+
+	// writes counts the changes made to the overflow, so Get can tell
+	// whether one ran while it read the overflow; guarded by lock
+	writes uint64
+}
+
+// NewTiered creates a TieredCache whose in-memory tier holds size entries
+// and spills over into overflow.
+func NewTiered(size int, overflow Overflow) (*TieredCache, error) {
+	if size <= 0 {
+		return nil, ErrInvalidSize
+	}
+	if overflow == nil {
+		return nil, ErrNoOverflow
+	}
+	c := &TieredCache{overflow: overflow}
+	lru, err := simplelru.NewLRUWithReason(size, 0, c.evicted)
+	if err != nil {
+		return nil, err
+	}
+	c.lru = lru
+	return c, nil
+}
+
+// evicted passes entries evicted for capacity to the overflow. It is called
+// with the lock held.
+func (c *TieredCache) evicted(key, value interface{}, reason simplelru.EvictReason) {
+	if reason == simplelru.ReasonCapacity {
+		c.overflow.Put(key, value)
+		c.writes++
+	}
+}
+
+// Get looks up a key's value in memory, updating its recent-ness, and
+// otherwise in the overflow. A value found in the overflow is moved back to
+// memory, which may evict another entry to the overflow, unless the key was
+// added in the meantime, in which case the newer value is returned. If the
+// overflow implements OverflowRemover the moved entry is deleted from it.
+func (c *TieredCache) Get(key interface{}) (value interface{}, ok bool) {
+	c.lock.Lock()
+	value, ok = c.lru.Get(key)
+	writes := c.writes
+	c.lock.Unlock()
+	if ok {
+		return value, true
+	}
+
+	// Read the overflow unlocked, as it may be slow
+	if value, ok = c.overflow.Get(key); !ok {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if newer, found := c.lru.Get(key); found {
+		return newer, true
+	}
+	if c.writes != writes {
+		// The overflow changed in the meantime and may no longer hold the
+		// value read, so read it again now that changes are held off
+		if value, ok = c.overflow.Get(key); !ok {
+			return nil, false
+		}
+	}
+	c.add(key, value)
+	return value, true
+}
+
+// Add adds a value to the in-memory tier, deleting any older copy of the
+// key from the overflow if it implements OverflowRemover. Returns true if
+// an eviction to the overflow occurred.
+func (c *TieredCache) Add(key, value interface{}) (evicted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.add(key, value)
+}
+
+// add stores a value in memory and deletes the key from the overflow, so
+// the overflow never holds a value older than the one in memory. It is
+// called with the lock held.
+func (c *TieredCache) add(key, value interface{}) (evicted bool) {
+	evicted = c.lru.Add(key, value)
+	if r, ok := c.overflow.(OverflowRemover); ok {
+		r.Remove(key)
+		c.writes++
+	}
+	return evicted
+}
+
+// Contains checks if a key is in the in-memory tier, without updating the
+// recent-ness. The overflow is not consulted.
+func (c *TieredCache) Contains(key interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Contains(key)
+}
+
+// Peek returns the key value (or undefined if not found) from memory or
+// else the overflow, without updating the "recently used"-ness of the key
+// or moving it back into memory.
+func (c *TieredCache) Peek(key interface{}) (value interface{}, ok bool) {
+	c.lock.Lock()
+	value, ok = c.lru.Peek(key)
+	c.lock.Unlock()
+	if ok {
+		return value, true
+	}
+	return c.overflow.Get(key)
+}
+
+// Remove removes the provided key from memory, and from the overflow if
+// it implements OverflowRemover. Otherwise a later Get may still find the
+// key in the overflow.
+func (c *TieredCache) Remove(key interface{}) (present bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	present = c.lru.Remove(key)
+	if r, ok := c.overflow.(OverflowRemover); ok {
+		r.Remove(key)
+		c.writes++
+	}
+	return present
+}
+
+// Keys returns a slice of the keys in memory, from oldest to newest.
+func (c *TieredCache) Keys() []interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Keys()
+}
+
+// Len returns the number of items in memory.
+func (c *TieredCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Len()
+}
+
+// Purge clears the in-memory tier without passing its entries to the
+// overflow, and clears the overflow too if it implements OverflowPurger.
+// Otherwise a later Get may still find purged keys in the overflow.
+func (c *TieredCache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lru.Purge()
+	if p, ok := c.overflow.(OverflowPurger); ok {
+		p.Purge()
+		c.writes++
+	}
+}
@@ -0,0 +1,253 @@
+package lru
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// mapOverflow is an Overflow backed by a map
+type mapOverflow struct {
+	lock  sync.Mutex
+	items map[interface{}]interface{}
+	gets  int
+}
+
+func newMapOverflow() *mapOverflow {
+	return &mapOverflow{items: make(map[interface{}]interface{})}
+}
+
+func (o *mapOverflow) Put(key, value interface{}) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.items[key] = value
+}
+
+func (o *mapOverflow) Get(key interface{}) (value interface{}, ok bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.gets++
+	value, ok = o.items[key]
+	return value, ok
+}
+
+func (o *mapOverflow) Remove(key interface{}) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	delete(o.items, key)
+}
+
+func (o *mapOverflow) Purge() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.items = make(map[interface{}]interface{})
+}
+
+// removeOnlyOverflow is a mapOverflow that cannot be purged
+type removeOnlyOverflow struct {
+	o *mapOverflow
+}
+
+func (r removeOnlyOverflow) Put(key, value interface{}) { r.o.Put(key, value) }
+
+func (r removeOnlyOverflow) Get(key interface{}) (interface{}, bool) { return r.o.Get(key) }
+
+func (r removeOnlyOverflow) Remove(key interface{}) { r.o.Remove(key) }
+
+func TestTieredCache(t *testing.T) {
+	if _, err := NewTiered(0, newMapOverflow()); err == nil {
+		t.Fatalf("should fail on a zero size")
+	}
+	if _, err := NewTiered(2, nil); !errors.Is(err, ErrNoOverflow) {
+		t.Fatalf("should fail without an overflow")
+	}
+
+	overflow := newMapOverflow()
+	l, err := NewTiered(2, overflow)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if !l.Add(3, 3) {
+		t.Fatalf("should have an eviction")
+	}
+	if l.Contains(1) || overflow.items[1] != 1 {
+		t.Fatalf("1 should have moved to the overflow: %v", overflow.items)
+	}
+
+	// Peek reads the overflow without moving the entry back
+	if v, ok := l.Peek(1); !ok || v != 1 || l.Contains(1) {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+
+	// Get moves it back, pushing out the oldest entry in memory
+	if v, ok := l.Get(1); !ok || v != 1 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if _, ok := overflow.items[1]; ok {
+		t.Fatalf("1 should have been removed from the overflow")
+	}
+	if !l.Contains(1) || l.Contains(2) || overflow.items[2] != 2 {
+		t.Fatalf("bad keys: %v, %v", l.Keys(), overflow.items)
+	}
+
+	// Memory hits do not touch the overflow
+	gets := overflow.gets
+	l.Get(1)
+	l.Get(3)
+	if overflow.gets != gets {
+		t.Fatalf("overflow should not be read on a hit")
+	}
+	if _, ok := l.Get(4); ok {
+		t.Fatalf("4 should not be found")
+	}
+
+	// Removal and purges do not spill over
+	l.Remove(2)
+	if _, ok := l.Get(2); ok {
+		t.Fatalf("2 should have been removed from the overflow")
+	}
+	l.Purge()
+	if l.Len() != 0 || len(overflow.items) != 0 {
+		t.Fatalf("bad: %v, %v", l.Len(), overflow.items)
+	}
+
+	// A newer value is not shadowed by a stale overflow copy after a purge
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	l.Get(1)
+	l.Add(1, 10)
+	l.Purge()
+	if v, ok := l.Get(1); ok {
+		t.Fatalf("stale value returned: %v", v)
+	}
+}
+
+// test that overwritten values do not come back from the overflow
+func TestTieredCache_Stale(t *testing.T) {
+	// Add deletes the older copy from the overflow
+	overflow := newMapOverflow()
+	l, err := NewTiered(1, removeOnlyOverflow{overflow})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add("a", 1)
+	l.Add("b", 1)
+	if overflow.items["a"] != 1 {
+		t.Fatalf("a should be in the overflow: %v", overflow.items)
+	}
+	l.Add("a", 2)
+	if _, ok := overflow.items["a"]; ok {
+		t.Fatalf("the older a should have been deleted: %v", overflow.items)
+	}
+	l.Purge()
+	if _, ok := l.Get("a"); ok {
+		t.Fatalf("a should not come back after the purge")
+	}
+	if v, ok := l.Get("b"); !ok || v != 1 {
+		t.Fatalf("b should still be in the unpurged overflow: %v, %v", v, ok)
+	}
+
+	// Purge clears an overflow that supports it
+	overflow = newMapOverflow()
+	l, err = NewTiered(1, overflow)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add("a", 1)
+	l.Add("b", 1)
+	l.Add("a", 2)
+	l.Purge()
+	if len(overflow.items) != 0 {
+		t.Fatalf("the overflow should have been purged: %v", overflow.items)
+	}
+	if v, ok := l.Get("a"); ok {
+		t.Fatalf("stale value returned: %v", v)
+	}
+}
+
+// blockingOverflow is a mapOverflow whose Get waits to be released once
+// started is closed
+type blockingOverflow struct {
+	*mapOverflow
+	started chan struct{}
+	release chan struct{}
+}
+
+func (o *blockingOverflow) Get(key interface{}) (value interface{}, ok bool) {
+	value, ok = o.mapOverflow.Get(key)
+	if o.started != nil {
+		close(o.started)
+		o.started = nil
+		<-o.release
+	}
+	return value, ok
+}
+
+func TestTieredCache_GetRemove(t *testing.T) {
+	overflow := &blockingOverflow{mapOverflow: newMapOverflow()}
+	l, err := NewTiered(1, overflow)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+
+	// Remove 1 while Get holds the value it read from the overflow
+	overflow.started = make(chan struct{})
+	overflow.release = make(chan struct{})
+	started := overflow.started
+	type result struct {
+		value interface{}
+		ok    bool
+	}
+	done := make(chan result)
+	go func() {
+		v, ok := l.Get(1)
+		done <- result{v, ok}
+	}()
+	<-started
+	l.Remove(1)
+	close(overflow.release)
+	if r := <-done; r.ok {
+		t.Fatalf("removed key returned: %v", r.value)
+	}
+	if l.Contains(1) {
+		t.Fatalf("removed key should not be back in memory")
+	}
+	if _, ok := overflow.items[1]; ok {
+		t.Fatalf("removed key should not be in the overflow")
+	}
+
+	// Concurrent gets and removes never leave a key in both tiers
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				key := j % 4
+				if (i+j)%3 == 0 {
+					l.Remove(key)
+					l.Add(key, key)
+				} else {
+					l.Get(key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, key := range l.Keys() {
+		overflow.lock.Lock()
+		_, dup := overflow.items[key]
+		overflow.lock.Unlock()
+		if dup {
+			t.Fatalf("%v is in both tiers", key)
+		}
+	}
+}